@@ -0,0 +1,73 @@
+package clog
+
+// Backtrace keeps the last Size entries below the logger's level and writes
+// them, marked as backfilled, right before an entry at Trigger or above, so
+// an error comes with the debug output that led to it.
+//
+// A Backtrace is guarded by the lock of the logger it is set on. Loggers
+// returned by Child get a buffer of their own.
+type Backtrace struct {
+	Size    int
+	Trigger Level
+
+	entries []*Entry
+	next    int
+}
+
+func NewBacktrace(size int) *Backtrace {
+	return &Backtrace{
+		Size:    size,
+		Trigger: LevelError,
+	}
+}
+
+func SetBacktrace(backtrace *Backtrace) *Logger {
+	return logger.SetBacktrace(backtrace)
+}
+
+func (l *Logger) SetBacktrace(backtrace *Backtrace) *Logger {
+	l.Backtrace = backtrace
+	return l
+}
+
+func Child() *Logger {
+	return logger.Child()
+}
+
+// Child returns a logger for one unit of work, such as a request. It writes
+// to the same output, but buffers its own backtrace so an error only pulls
+// in the entries of the work that failed.
+func (l *Logger) Child() *Logger {
+	c := l.clone()
+	if l.Backtrace != nil {
+		c.Backtrace = &Backtrace{
+			Size:    l.Backtrace.Size,
+			Trigger: l.Backtrace.Trigger,
+		}
+	}
+	return c
+}
+
+// add keeps e, dropping the oldest entry once the buffer is full; the
+// logger's lock must be held.
+func (b *Backtrace) add(e *Entry) {
+	if b.Size <= 0 {
+		return
+	}
+	if len(b.entries) < b.Size {
+		b.entries = append(b.entries, e)
+		return
+	}
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+}
+
+// drain returns the kept entries, oldest first, and empties the buffer;
+// the lock must be held.
+func (b *Backtrace) drain() []*Entry {
+	entries := make([]*Entry, 0, len(b.entries))
+	entries = append(entries, b.entries[b.next:]...)
+	entries = append(entries, b.entries[:b.next]...)
+	b.entries, b.next = nil, 0
+	return entries
+}
@@ -0,0 +1,60 @@
+package clog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBacktrace(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetBacktrace(NewBacktrace(2))
+	l.Debug().Msg("one")
+	l.Debug().Msg("two")
+	l.Trace().Msg("three")
+	l.Info().Msg("shown")
+	if got := uncolored(b.String()); got != "• shown\n" {
+		t.Fatalf("entries below the level written: %q", got)
+	}
+
+	l.Error().Msg("failed")
+	want := `• shown
+• two (backfilled)
+• three (backfilled)
+✖ failed
+`
+	if got := uncolored(b.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// the buffer is emptied by the backfill
+	b.Reset()
+	l.Error().Msg("again")
+	if got := uncolored(b.String()); got != "✖ again\n" {
+		t.Errorf("backfilled twice: %q", got)
+	}
+	if got := l.Count(LevelDebug, LevelTrace); got != 2 {
+		t.Errorf("only backfilled entries are counted, got %d", got)
+	}
+}
+
+func TestBacktraceChild(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetFormatter(&JSONFormatter{}).SetBacktrace(NewBacktrace(10))
+	a, c := l.Child(), l.Child()
+	a.Debug().Msg("a")
+	c.Debug().Msg("c")
+	c.Error().Msg("c failed")
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %q", lines)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["msg"] != "c" || got["backfilled"] != true {
+		t.Errorf("got %s", lines[0])
+	}
+}
@@ -0,0 +1,255 @@
+package clog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Batching configures how a network sink ships entries: queued without
+// blocking the logger, sent in batches of up to BatchSize or every
+// BatchInterval, and retried with exponential backoff. Entries logged while
+// the queue is full are dropped and counted, unless Block is set. The
+// settings are read when the sink receives its first entry, with zero
+// settings taking their default value; a negative MaxRetries turns retrying
+// off.
+type Batching struct {
+	BatchSize     int
+	BatchInterval time.Duration
+	QueueSize     int
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	MaxBackoff    time.Duration
+	// Block makes logging wait for room in a full queue instead of dropping
+	// the entry.
+	Block bool
+	// OnError is called from the sending goroutine with errors that made a
+	// batch get dropped.
+	OnError func(err error)
+}
+
+func defaultBatching() Batching {
+	return Batching{
+		BatchSize:     512,
+		BatchInterval: time.Second,
+		QueueSize:     4096,
+		MaxRetries:    5,
+		RetryBackoff:  500 * time.Millisecond,
+		MaxBackoff:    30 * time.Second,
+	}
+}
+
+// withDefaults fills the zero settings from defaultBatching, as a zero
+// BatchSize would send every entry on its own and a zero MaxBackoff would
+// retry without waiting.
+func (c Batching) withDefaults() Batching {
+	d := defaultBatching()
+	if c.BatchSize <= 0 {
+		c.BatchSize = d.BatchSize
+	}
+	if c.BatchInterval <= 0 {
+		c.BatchInterval = d.BatchInterval
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = d.QueueSize
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = d.MaxRetries
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = d.RetryBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = d.MaxBackoff
+	}
+	return c
+}
+
+// batcher queues items and passes them to send in batches from a goroutine
+// started with the first item.
+type batcher[T any] struct {
+	mu      sync.Mutex
+	closed  bool
+	queue   chan T
+	stop    chan struct{}
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+func (b *batcher[T]) add(config Batching, item T, send func([]T) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		b.dropped.Add(1)
+		return
+	}
+	if b.queue == nil {
+		config = config.withDefaults()
+		b.queue = make(chan T, config.QueueSize)
+		b.stop = make(chan struct{})
+		b.done = make(chan struct{})
+		go b.run(config, send)
+	}
+	if config.Block {
+		b.queue <- item
+		return
+	}
+	select {
+	case b.queue <- item:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+func (b *batcher[T]) run(config Batching, send func([]T) error) {
+	defer close(b.done)
+	ticker := time.NewTicker(config.BatchInterval)
+	defer ticker.Stop()
+
+	var batch []T
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := retry(config, b.stop, func() error {
+			err := send(batch)
+			// what was delivered is not sent again
+			var partial *partialError
+			if errors.As(err, &partial) {
+				batch = batch[partial.sent:]
+			}
+			return err
+		})
+		if err != nil && config.OnError != nil {
+			config.OnError(err)
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case item, ok := <-b.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) >= config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// closeTimeout bounds how long closing a sink keeps retrying to send what
+// is queued.
+const closeTimeout = 10 * time.Second
+
+// close sends what is queued and waits for it.
+func (b *batcher[T]) close() {
+	b.mu.Lock()
+	if b.closed || b.queue == nil {
+		b.closed = true
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.queue)
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-time.After(closeTimeout):
+		close(b.stop)
+		<-b.done
+	}
+}
+
+// retryError is a failure worth retrying, optionally after a delay asked for
+// by the server.
+type retryError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryError) Unwrap() error {
+	return e.err
+}
+
+// partialError reports that a batch failed after its first sent items were
+// delivered, as when datagrams are written one by one.
+type partialError struct {
+	sent int
+	err  error
+}
+
+func (e *partialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+func retry(config Batching, stop chan struct{}, fn func() error) error {
+	backoff := config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		var retryable *retryError
+		if err == nil || !errors.As(err, &retryable) || attempt >= config.MaxRetries {
+			return err
+		}
+		wait := backoff
+		if retryable.after > 0 {
+			wait = retryable.after
+		}
+		select {
+		case <-stop:
+			return err
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, config.MaxBackoff)
+	}
+}
+
+// postHTTP sends body and classifies failures: network errors, 429 and 5xx
+// responses can be retried, other responses cannot.
+func postHTTP(client *http.Client, url, contentType string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &retryError{err: err}
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("clog: %s: %s %s", url, resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		after, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &retryError{err: err, after: time.Duration(after) * time.Second}
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package clog
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchingDefaults(t *testing.T) {
+	if got, want := (Batching{}).withDefaults(), defaultBatching(); !reflect.DeepEqual(got, want) {
+		t.Errorf("zero settings: got %+v, want %+v", got, want)
+	}
+
+	config := Batching{BatchSize: 3, MaxRetries: -1}.withDefaults()
+	if config.BatchSize != 3 || config.MaxRetries != -1 {
+		t.Errorf("set fields were changed: %+v", config)
+	}
+}
+
+func TestBatcherZeroConfig(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]int
+	)
+	var b batcher[int]
+	send := func(batch []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, append([]int(nil), batch...))
+		return nil
+	}
+	for i := range 5 {
+		b.add(Batching{}, i, send)
+	}
+	b.close()
+
+	// the default batch size and interval ship all five in one batch
+	if want := [][]int{{0, 1, 2, 3, 4}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("got batches %v, want %v", batches, want)
+	}
+}
+
+func TestBatcherPartialRetry(t *testing.T) {
+	var sent [][]int
+	send := func(batch []int) error {
+		sent = append(sent, append([]int(nil), batch...))
+		if len(sent) == 1 {
+			return &partialError{sent: 2, err: &retryError{err: errors.New("refused")}}
+		}
+		return nil
+	}
+	var b batcher[int]
+	config := Batching{BatchInterval: time.Hour, RetryBackoff: time.Millisecond}
+	for i := range 4 {
+		b.add(config, i, send)
+	}
+	b.close()
+
+	if want := [][]int{{0, 1, 2, 3}, {2, 3}}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("got sends %v, want %v", sent, want)
+	}
+}
+
+func TestBatcherRetryLimit(t *testing.T) {
+	var attempts int
+	var failed error
+	config := Batching{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+		OnError:      func(err error) { failed = err },
+	}
+	var b batcher[int]
+	b.add(config, 1, func([]int) error {
+		attempts++
+		return &retryError{err: errors.New("unavailable")}
+	})
+	b.close()
+
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want 3", attempts)
+	}
+	if failed == nil {
+		t.Error("OnError was not called")
+	}
+}
@@ -0,0 +1,148 @@
+package clog
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Entry kinds for output that structures a run rather than reporting an
+// event. Structured formatters emit the kind as a field.
+const (
+	KindSection = "section"
+	KindBanner  = "banner"
+	KindBox     = "box"
+)
+
+type BlockStyle struct {
+	Title  lipgloss.Style
+	Rule   lipgloss.Style
+	Border lipgloss.Color
+}
+
+var BlockStyles = BlockStyle{
+	Title:  lipgloss.NewStyle().Bold(true),
+	Rule:   lipgloss.NewStyle().Foreground(gray),
+	Border: lipgloss.Color("63"),
+}
+
+var asciiBorder = lipgloss.Border{
+	Top:         "-",
+	Bottom:      "-",
+	Left:        "|",
+	Right:       "|",
+	TopLeft:     "+",
+	TopRight:    "+",
+	BottomLeft:  "+",
+	BottomRight: "+",
+}
+
+const defaultRuleWidth = 60
+
+func Section(title string) {
+	logger.Section(title)
+}
+
+func Banner(text string) {
+	logger.Banner(text)
+}
+
+func Box(title string, fields any) {
+	logger.Box(title, fields)
+}
+
+// Section logs a header separating the phases of a run.
+func (l *Logger) Section(title string) {
+	e := l.Info()
+	e.Kind = KindSection
+	e.msg(title)
+}
+
+func (l *Logger) Banner(text string) {
+	e := l.Info()
+	e.Kind = KindBanner
+	e.msg(text)
+}
+
+// Box logs a bordered summary of fields, which may be a map, a struct or
+// anything that resolves to an Object.
+func (l *Logger) Box(title string, fields any) {
+	e := l.Info()
+	e.Kind = KindBox
+	expand := &treeWriter{maxDepth: 1, maxItems: math.MaxInt}
+	if nodes, ok := expand.children(resolve(fields), 0); ok {
+		for _, node := range nodes {
+			if node.more == 0 {
+				e.Any(node.key, node.value)
+			}
+		}
+	}
+	e.msg(title)
+}
+
+func isBlock(kind string) bool {
+	return kind == KindSection || kind == KindBanner || kind == KindBox
+}
+
+func (f *PrettyFormatter) renderBlock(e *Entry, tree *treeWriter, nodes []treeNode) []byte {
+	noColor := e.Logger.NoColor
+	border := lipgloss.RoundedBorder()
+	if noColor {
+		border = asciiBorder
+	}
+	width := f.width(e)
+	if width <= 0 {
+		width = defaultRuleWidth
+	}
+
+	var out string
+	switch e.Kind {
+	case KindSection:
+		title := " " + e.Message + " "
+		fill := "─"
+		if noColor {
+			fill = "-"
+		}
+		rest := max(width-lipgloss.Width(title)-2, 2)
+		out = "\n" + tree.palette.block.Rule.Render(strings.Repeat(fill, 2)) +
+			tree.palette.block.Title.Render(title) +
+			tree.palette.block.Rule.Render(strings.Repeat(fill, rest))
+
+	case KindBanner:
+		out = lipgloss.NewStyle().
+			Border(border).
+			BorderForeground(tree.palette.block.Border).
+			Padding(0, 2).
+			Render(tree.palette.block.Title.Render(e.Message))
+
+	case KindBox:
+		var b bytes.Buffer
+		b.WriteString(tree.palette.block.Title.Render(e.Message))
+		keyWidth := 0
+		for _, node := range nodes {
+			keyWidth = max(keyWidth, lipgloss.Width(e.Logger.sanitize(node.key)))
+		}
+		for _, node := range nodes {
+			key := e.Logger.sanitize(node.key)
+			value, render := tree.scalar(node.value, true)
+			b.WriteString("\n")
+			b.WriteString(tree.keyStyle.Render(key + strings.Repeat(" ", keyWidth-lipgloss.Width(key)) + "  "))
+			b.WriteString(render(strings.ReplaceAll(value, "\n", " ")))
+		}
+		out = lipgloss.NewStyle().
+			Border(border).
+			BorderForeground(tree.palette.block.Border).
+			Padding(0, 1).
+			Render(b.String())
+	}
+	return f.indent(e, []byte(out))
+}
+
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+func stripANSI(b []byte) []byte {
+	return ansiSequence.ReplaceAll(b, nil)
+}
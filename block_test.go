@@ -0,0 +1,60 @@
+package clog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	l, b := bufferLogger()
+	l.WithColor(false).SetWidth(30)
+	l.Section("Build")
+	l.Banner("v1.2")
+	l.Box("Summary", map[string]any{"files": 3, "target": "linux"})
+	want := `
+-- Build ---------------------
++--------+
+|  v1.2  |
++--------+
++---------------+
+| Summary       |
+| files   3     |
+| target  linux |
++---------------+
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	b.Reset()
+	task := l.Task("t")
+	task.Section("inner")
+	if got := b.String(); strings.Contains(got, "\x1b") || !strings.Contains(got, "\n  -- inner ---") {
+		t.Errorf("task section: %q", got)
+	}
+}
+
+func TestBlockColors(t *testing.T) {
+	l, b := bufferLogger()
+	l.WithColor(true).SetWidth(30)
+	l.Banner("hi")
+	if got := b.String(); !strings.Contains(got, "╭") || !strings.Contains(got, "\x1b[") {
+		t.Errorf("banner: %q", got)
+	}
+}
+
+func TestBlockKinds(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetFormatter(&JSONFormatter{})
+	l.Box("Totals", struct {
+		Files int `json:"files"`
+	}{3})
+	var got map[string]any
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["kind"] != KindBox || got["msg"] != "Totals" || got["files"] != 3.0 {
+		t.Errorf("got %s", b.String())
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ef4b3f/clog"
+)
+
+// indexStride is how many lines apart the index records offsets. Reading a
+// line scans at most that many lines from the closest recorded offset.
+const indexStride = 64
+
+// index finds the lines of a file in the background, so a view can show
+// and seek to any of them before the whole file is read. It keeps reading
+// as the file grows, and starts over when it is truncated.
+type index struct {
+	f *os.File
+
+	mu      sync.Mutex
+	offsets []int64 // offset of every indexStride-th line
+	levels  []int8  // level of every line, -1 when not known
+	size    int64   // end of the indexed lines
+	total   int64   // size of the file when last read
+	partial bool    // the last line has no newline yet
+	start   int64   // offset of the last line, while partial
+	resets  int     // times the file was truncated
+}
+
+func newIndex(f *os.File) *index {
+	ix := &index{f: f}
+	go func() {
+		for {
+			ix.scan()
+			time.Sleep(500 * time.Millisecond)
+		}
+	}()
+	return ix
+}
+
+func (ix *index) count() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.levels)
+}
+
+// state returns the number of lines, of which the last may still grow when
+// partial, and the number of times the file was truncated.
+func (ix *index) state() (lines int, partial bool, resets int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.levels), ix.partial, ix.resets
+}
+
+// progress returns how much of the file has been indexed, from 0 to 1.
+func (ix *index) progress() float64 {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.total == 0 {
+		return 1
+	}
+	return float64(ix.size) / float64(ix.total)
+}
+
+func (ix *index) level(line int) int8 {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if line < 0 || line >= len(ix.levels) {
+		return -1
+	}
+	return ix.levels[line]
+}
+
+// read returns up to n lines from line on, without their newlines.
+func (ix *index) read(line, n int) [][]byte {
+	ix.mu.Lock()
+	if line < 0 || line >= len(ix.levels) {
+		ix.mu.Unlock()
+		return nil
+	}
+	n = min(n, len(ix.levels)-line)
+	offset, end := ix.offsets[line/indexStride], ix.size
+	ix.mu.Unlock()
+
+	r := bufio.NewReader(io.NewSectionReader(ix.f, offset, end-offset))
+	skip := line % indexStride
+	lines := make([][]byte, 0, n)
+	for i := 0; i < skip+n; i++ {
+		text, err := r.ReadBytes('\n')
+		if i >= skip {
+			lines = append(lines, bytes.TrimRight(text, "\r\n"))
+		}
+		if err != nil {
+			break
+		}
+	}
+	return lines
+}
+
+// scan indexes the lines added to the file since the last scan.
+func (ix *index) scan() {
+	info, err := ix.f.Stat()
+	if err != nil {
+		return
+	}
+	ix.mu.Lock()
+	if info.Size() < ix.size {
+		ix.offsets, ix.levels, ix.size, ix.partial = nil, nil, 0, false
+		ix.resets++
+	}
+	if info.Size() == ix.size {
+		ix.total = ix.size
+		ix.mu.Unlock()
+		return
+	}
+	// a line without a newline is read again now that it may be complete
+	if ix.partial {
+		ix.drop()
+	}
+	offset, line := ix.size, len(ix.levels)
+	ix.total = info.Size()
+	ix.mu.Unlock()
+
+	var offsets []int64
+	var levels []int8
+	commit := func(end int64, partial bool) {
+		ix.mu.Lock()
+		ix.offsets = append(ix.offsets, offsets...)
+		ix.levels = append(ix.levels, levels...)
+		ix.size, ix.partial = end, partial
+		ix.mu.Unlock()
+		offsets, levels = offsets[:0], levels[:0]
+	}
+
+	r := bufio.NewReaderSize(io.NewSectionReader(ix.f, offset, info.Size()-offset), 1<<20)
+	for {
+		text, err := r.ReadSlice('\n')
+		length := int64(len(text))
+		level := sniffLevel(text)
+		for errors.Is(err, bufio.ErrBufferFull) {
+			text, err = r.ReadSlice('\n')
+			length += int64(len(text))
+		}
+		if length == 0 {
+			break
+		}
+		if line%indexStride == 0 {
+			offsets = append(offsets, offset)
+		}
+		levels = append(levels, level)
+		start := offset
+		offset += length
+		line++
+		if err != nil {
+			ix.mu.Lock()
+			ix.start = start
+			ix.mu.Unlock()
+			commit(offset, true)
+			return
+		}
+		if len(levels) == 1<<14 {
+			commit(offset, false)
+		}
+	}
+	commit(offset, false)
+}
+
+// drop forgets the last line; ix.mu must be held.
+func (ix *index) drop() {
+	n := len(ix.levels) - 1
+	ix.levels = ix.levels[:n]
+	if n%indexStride == 0 {
+		ix.offsets = ix.offsets[:len(ix.offsets)-1]
+	}
+	ix.size, ix.partial = ix.start, false
+}
+
+var levelKeys = [][]byte{
+	[]byte(`"level":`),
+	[]byte(`"lvl":`),
+	[]byte(`"severity":`),
+	[]byte("level="),
+	[]byte("lvl="),
+}
+
+// sniffLevel finds the level of a JSON or logfmt line without parsing it,
+// which is much faster than parsing for indexing.
+func sniffLevel(line []byte) int8 {
+	for _, key := range levelKeys {
+		i := bytes.Index(line, key)
+		if i < 0 {
+			continue
+		}
+		value := bytes.TrimLeft(line[i+len(key):], " ")
+		value = bytes.TrimPrefix(value, []byte(`"`))
+		if end := bytes.IndexAny(value, "\" ,}\r\n"); end >= 0 {
+			value = value[:end]
+		}
+		if level, ok := clog.ParseLevelValue(string(value)); ok {
+			return int8(level)
+		}
+	}
+	return -1
+}
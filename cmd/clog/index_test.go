@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ef4b3f/clog"
+)
+
+// scannedIndex returns an index of the file at path, read once.
+func scannedIndex(t *testing.T, path string) *index {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	ix := &index{f: f}
+	ix.scan()
+	return ix
+}
+
+func appendFile(t *testing.T, path, text string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	for i := range 150 {
+		if i%3 == 2 {
+			appendFile(t, path, fmt.Sprintf("plain %d\n", i))
+		} else {
+			appendFile(t, path, fmt.Sprintf(`{"level":"%s","msg":"line %d"}`+"\n", []string{"info", "error"}[i%3], i))
+		}
+	}
+	ix := scannedIndex(t, path)
+
+	if n := ix.count(); n != 150 {
+		t.Fatalf("indexed %d lines, want 150", n)
+	}
+	if ix.level(0) != int8(clog.LevelInfo) || ix.level(100) != int8(clog.LevelError) || ix.level(149) != -1 {
+		t.Errorf("levels %d %d %d", ix.level(0), ix.level(100), ix.level(149))
+	}
+	// line 130 is past the second recorded offset
+	want := [][]byte{[]byte(`{"level":"error","msg":"line 130"}`), []byte("plain 131")}
+	if got := ix.read(130, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("read(130, 2) = %q, want %q", got, want)
+	}
+	if got := ix.read(149, 5); len(got) != 1 {
+		t.Errorf("read past the end returned %q", got)
+	}
+
+	appendFile(t, path, `level=warn msg="half`)
+	ix.scan()
+	if lines, partial, _ := ix.state(); lines != 151 || !partial || ix.level(150) != int8(clog.LevelWarn) {
+		t.Fatalf("after a partial line: %d lines, partial %v", lines, partial)
+	}
+	appendFile(t, path, ` done"`+"\nlevel=debug msg=next\n")
+	ix.scan()
+	want = [][]byte{[]byte(`level=warn msg="half done"`), []byte("level=debug msg=next")}
+	if lines, partial, _ := ix.state(); lines != 152 || partial {
+		t.Fatalf("after completing it: %d lines, partial %v", lines, partial)
+	}
+	if got := ix.read(150, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("read(150, 2) = %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("level=fatal msg=restarted\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ix.scan()
+	if lines, _, resets := ix.state(); lines != 1 || resets != 1 || ix.level(0) != int8(clog.LevelFatal) {
+		t.Errorf("after truncating: %d lines, %d resets", lines, resets)
+	}
+}
+
+func TestSniffLevel(t *testing.T) {
+	for line, want := range map[string]int8{
+		`{"level":"warn","msg":"a"}`:       int8(clog.LevelWarn),
+		`{"level": "WARN+2","msg":"a"}`:    int8(clog.LevelWarn),
+		`{"lvl":"dbug"}`:                   -1,
+		`{"severity":"critical"}`:          int8(clog.LevelFatal),
+		`{"name":"app","level":50}`:        int8(clog.LevelError),
+		`time=2024-05-01 level=info msg=a`: int8(clog.LevelInfo),
+		`lvl=trace`:                        int8(clog.LevelTrace),
+		`starting up`:                      -1,
+	} {
+		if got := sniffLevel([]byte(line)); got != want {
+			t.Errorf("sniffLevel(%q) = %d, want %d", line, got, want)
+		}
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("j\x1b[A\x1b[6~\x1bOH\x1b\r\x7fé\x01"))
+	want := []string{"j", "up", "pgdown", "home", "esc", "enter", "backspace", "é"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
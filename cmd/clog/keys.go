@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// readKeys sends the keys read from in, named after the key for special
+// keys and as the character typed otherwise, until in fails.
+func readKeys(in io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := in.Read(buf)
+		for _, key := range decodeKeys(buf[:n]) {
+			keys <- key
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+var csiKeys = map[string]string{
+	"A": "up", "B": "down", "C": "right", "D": "left",
+	"H": "home", "F": "end", "1~": "home", "7~": "home", "4~": "end", "8~": "end",
+	"5~": "pgup", "6~": "pgdown", "3~": "delete",
+}
+
+var controlKeys = map[byte]string{
+	'\r': "enter", '\n': "enter", '\t': "tab",
+	0x7f: "backspace", 0x08: "backspace",
+	0x03: "ctrl-c", 0x02: "pgup", 0x06: "pgdown", 0x04: "ctrl-d", 0x15: "ctrl-u",
+}
+
+func decodeKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			// an escape sequence, CSI or SS3, or the escape key on its own
+			if len(b) > 2 && (b[1] == '[' || b[1] == 'O') {
+				end := 2
+				for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+					end++
+				}
+				if end < len(b) {
+					if key, ok := csiKeys[string(b[2:end+1])]; ok {
+						keys = append(keys, key)
+					}
+					b = b[end+1:]
+					continue
+				}
+			}
+			keys = append(keys, "esc")
+			b = b[1:]
+			continue
+		}
+		if key, ok := controlKeys[b[0]]; ok {
+			keys = append(keys, key)
+			b = b[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(b)
+		if r >= ' ' {
+			keys = append(keys, string(r))
+		}
+		b = b[size:]
+	}
+	return keys
+}
@@ -0,0 +1,253 @@
+// Command clog pretty-prints JSON and logfmt logs, such as those written by
+// clog's JSON formatter, zap, zerolog, slog, logrus or bunyan.
+//
+//	clog [flags] [file ...]
+//	clog view [flags] file
+//
+// It reads the files, or stdin, and passes lines it cannot parse through
+// unchanged. The view command browses a file interactively instead.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ef4b3f/clog"
+	"github.com/mattn/go-isatty"
+)
+
+type viewer struct {
+	mu      sync.Mutex
+	logger  *clog.Logger
+	out     io.Writer
+	filter  *clog.Filter
+	include []string
+	exclude []string
+	caller  bool
+	// plain escapes the lines that are not entries, on a terminal
+	plain bool
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "view" {
+		runView(os.Args[2:])
+		return
+	}
+
+	flags := flag.NewFlagSet("clog", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: clog [flags] [file ...]\n       clog view [flags] file\n\nflags:\n")
+		flags.PrintDefaults()
+	}
+	level := clog.LevelTrace
+	flags.TextVar(&level, "level", clog.LevelTrace, "lowest `level` to show")
+	query := flags.String("filter", "", "show only the entries matching `expression`, such as 'level>=warn and msg~timeout'")
+	include := flags.String("include", "", "comma separated `patterns` of the fields to show")
+	exclude := flags.String("exclude", "", "comma separated `patterns` of the fields to hide")
+	theme := flags.String("theme", "default", "color `theme`: default, light or mono")
+	color := flags.String("color", "auto", "color output: auto, always or never")
+	follow := flags.Bool("f", false, "keep reading files as they grow")
+	showTime := flags.Bool("time", true, "show the time")
+	timeFormat := flags.String("time-format", "2006-01-02 15:04:05.000", "time `layout`")
+	levelText := flags.Bool("level-text", true, "show the level name")
+	caller := flags.Bool("caller", true, "show the caller")
+	compact := flags.Bool("compact", false, "show fields on the message line when they fit")
+	_ = flags.Parse(os.Args[1:])
+
+	if err := clog.SetTheme(*theme); err != nil {
+		fatal(err)
+	}
+	filter, err := clog.ParseFilter(*query)
+	if err != nil {
+		fatal(err)
+	}
+	// the logs are shown as they were written, so nothing is redacted
+	logger := clog.New().
+		SetRedactor(nil).
+		SetWriter(os.Stdout).
+		SetLogLevel(level).
+		SetTimeFormat(*timeFormat).
+		WithTimestamp(*showTime).
+		WithLevelText(*levelText).
+		WithCompact(*compact)
+	switch *color {
+	case "auto":
+		if !isatty.IsTerminal(os.Stdout.Fd()) {
+			logger.WithColor(false)
+		}
+	case "always":
+		logger.WithColor(true)
+	case "never":
+		logger.WithColor(false)
+	default:
+		fatal(fmt.Errorf("unknown color mode %q", *color))
+	}
+
+	v := &viewer{
+		logger:  logger,
+		out:     os.Stdout,
+		filter:  filter,
+		include: splitList(*include),
+		exclude: splitList(*exclude),
+		caller:  *caller,
+		plain:   isatty.IsTerminal(os.Stdout.Fd()),
+	}
+	if flags.NArg() == 0 {
+		if err := v.read(os.Stdin, false); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	var failed bool
+	for _, name := range flags.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "clog:", err)
+			failed = true
+			continue
+		}
+		read := func() {
+			defer f.Close()
+			if err := v.read(f, *follow); err != nil {
+				fmt.Fprintln(os.Stderr, "clog:", err)
+			}
+		}
+		if *follow {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				read()
+			}()
+		} else {
+			read()
+		}
+	}
+	wg.Wait()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// read shows each line of f. With follow, it waits for f to grow at the
+// end, and starts over when f is truncated.
+func (v *viewer) read(f *os.File, follow bool) error {
+	r := bufio.NewReader(f)
+	var line []byte
+	var offset int64
+	for {
+		chunk, err := r.ReadBytes('\n')
+		offset += int64(len(chunk))
+		line = append(line, chunk...)
+		if err == nil {
+			v.show(line)
+			line = nil
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		if !follow {
+			if len(line) > 0 {
+				v.show(line)
+			}
+			return nil
+		}
+		time.Sleep(250 * time.Millisecond)
+		if info, err := f.Stat(); err == nil && info.Size() < offset {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			r.Reset(f)
+			line, offset = nil, 0
+		}
+	}
+}
+
+func (v *viewer) show(line []byte) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	e, err := v.logger.ParseLine(line)
+	if err != nil {
+		if v.plain {
+			line = []byte(plainText(bytes.TrimRight(line, "\r\n")))
+		}
+		if len(line) > 0 && line[len(line)-1] != '\n' {
+			line = append(line, '\n')
+		}
+		_, _ = v.out.Write(line)
+		return
+	}
+	// filter before fields are left out, so any field can be queried
+	if !v.filter.Match(e) {
+		return
+	}
+	for _, key := range e.Fields.Keys() {
+		if !v.keep(key) {
+			e.Fields.Delete(key)
+		}
+	}
+	if !v.caller {
+		e.Caller = ""
+	}
+	v.logger.Log(e)
+}
+
+// plainText makes a line that is not an entry safe to show on a terminal,
+// replacing control characters, C1 controls included, and bidirectional
+// overrides.
+func plainText(text []byte) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r < ' ' || r == 0x7f || r >= 0x80 && r < 0xa0:
+			return '?'
+		case r >= 0x202a && r <= 0x202e || r >= 0x2066 && r <= 0x2069:
+			return '?'
+		}
+		return r
+	}, string(text))
+}
+
+func (v *viewer) keep(key string) bool {
+	if len(v.include) > 0 && !matchAny(v.include, key) {
+		return false
+	}
+	return !matchAny(v.exclude, key)
+}
+
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "clog:", strings.TrimPrefix(err.Error(), "clog: "))
+	os.Exit(2)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ef4b3f/clog"
+)
+
+func testViewer() (*viewer, *bytes.Buffer) {
+	var b bytes.Buffer
+	logger := clog.New().
+		SetRedactor(nil).
+		SetWriter(&b).
+		SetLogLevel(clog.LevelInfo).
+		WithColor(false)
+	return &viewer{logger: logger, out: &b}, &b
+}
+
+func TestShow(t *testing.T) {
+	v, b := testViewer()
+	v.exclude = []string{"secret*"}
+	v.show([]byte(`{"level":"warn","msg":"disk low","free":12,"secret_key":"x","caller":"main.go:3"}` + "\n"))
+	v.show([]byte(`{"level":"debug","msg":"hidden"}` + "\n"))
+	v.show([]byte("starting up"))
+
+	out := b.String()
+	for _, want := range []string{"disk low", "free", "12", "starting up\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"hidden", "secret_key", "main.go:3"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output has %q:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestShowFilter(t *testing.T) {
+	v, b := testViewer()
+	v.filter = clog.MustParseFilter("user=ann")
+	// the field is matched before it is left out
+	v.exclude = []string{"user"}
+	v.show([]byte(`{"msg":"kept","user":"ann"}`))
+	v.show([]byte(`{"msg":"dropped","user":"bob"}`))
+
+	if out := b.String(); !strings.Contains(out, "kept") || strings.Contains(out, "dropped") || strings.Contains(out, "ann") {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestShowInclude(t *testing.T) {
+	v, b := testViewer()
+	v.include = []string{"user.*"}
+	v.caller = true
+	v.show([]byte(`level=info msg=login user.id=7 user.name=ann ip=10.0.0.1 caller=auth.go:9`))
+
+	out := b.String()
+	for _, want := range []string{"user.id", "user.name", "auth.go:9"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "10.0.0.1") {
+		t.Errorf("excluded field shown:\n%s", out)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	v, b := testViewer()
+	v.plain = true
+	v.show([]byte("a\tb\x1b[2Jc\u202ed\u0085\r\n"))
+	if got, want := b.String(), "a b?[2Jc?d?\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if got := splitList(" a, ,b*,"); len(got) != 2 || got[0] != "a" || got[1] != "b*" {
+		t.Errorf("got %q", got)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("got %q for an empty list", got)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package main
+
+import "errors"
+
+func makeRaw() (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this system")
+}
+
+func terminalSize() (width, height int) {
+	return 80, 24
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ef4b3f/clog"
+	"github.com/mattn/go-isatty"
+	"github.com/muesli/reflow/truncate"
+)
+
+const viewHelp = "j/k move  space expand  x expand all  / search  n/N next  e/E error  l/L level  & filter  f follow  g/G top/end  q quit"
+
+var (
+	statusStyle = lipgloss.NewStyle().Reverse(true)
+	cursorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Bold(true)
+	hintStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+)
+
+// view is an interactive viewer for a log file. Entries are shown collapsed
+// to one line each and can be expanded to their field trees.
+type view struct {
+	name   string
+	ix     *index
+	logger *clog.Logger
+	out    bytes.Buffer
+
+	width, height int
+	top, cursor   int // positions in the shown entries
+	shown         int // entries on screen in the last frame
+
+	minLevel clog.Level
+	filter   *clog.Filter
+	visible  []int // the lines shown, when filtering
+	scanned  int   // lines checked for visible
+	anchor   int   // line to keep the cursor on while filtering
+	resets   int   // truncations of the file seen
+	partial  int   // the line that may still grow, or -1
+
+	expandAll bool
+	toggled   map[int]bool
+	rendered  map[renderKey][]string
+
+	search *regexp.Regexp
+	follow bool
+
+	prompt string // "/" or "&" while reading a search or filter
+	input  []rune
+	status string
+	frame  string
+}
+
+type renderKey struct {
+	line     int
+	expanded bool
+}
+
+func runView(args []string) {
+	flags := flag.NewFlagSet("clog view", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: clog view [flags] file\n\n%s\n\nflags:\n", viewHelp)
+		flags.PrintDefaults()
+	}
+	level := clog.LevelTrace
+	flags.TextVar(&level, "level", clog.LevelTrace, "lowest `level` to show")
+	query := flags.String("filter", "", "show only the entries matching `expression`")
+	theme := flags.String("theme", "default", "color `theme`: default, light or mono")
+	follow := flags.Bool("f", false, "start in follow mode")
+	timeFormat := flags.String("time-format", "2006-01-02 15:04:05.000", "time `layout`")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := clog.SetTheme(*theme); err != nil {
+		fatal(err)
+	}
+	filter, err := clog.ParseFilter(*query)
+	if err != nil {
+		fatal(err)
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
+		fatal(fmt.Errorf("view needs a terminal"))
+	}
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	defer f.Close()
+
+	v := &view{
+		name:     filepath.Base(flags.Arg(0)),
+		ix:       newIndex(f),
+		minLevel: level,
+		filter:   filter,
+		follow:   *follow,
+		partial:  -1,
+		toggled:  map[int]bool{},
+		rendered: map[renderKey][]string{},
+	}
+	v.logger = clog.New().
+		SetRedactor(nil).
+		SetWriter(&v.out).
+		SetLogLevel(clog.LevelTrace).
+		SetTimeFormat(*timeFormat).
+		WithTimestamp(true).
+		WithLevelText(true)
+
+	restore, err := makeRaw()
+	if err != nil {
+		fatal(err)
+	}
+	// the alternate screen, without a cursor
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+	v.run()
+}
+
+func (v *view) run() {
+	keys := make(chan string, 64)
+	go readKeys(os.Stdin, keys)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		// the size is polled, as there is no resize signal on every system
+		v.resize(terminalSize())
+		v.refresh()
+		v.draw()
+		select {
+		case key, ok := <-keys:
+			if !ok || !v.key(key) {
+				return
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+func (v *view) resize(width, height int) {
+	if width == v.width && height == v.height {
+		return
+	}
+	v.width, v.height = max(width, 10), max(height, 2)
+	// leave the last column free, so a full row does not wrap
+	v.logger.SetWidth(v.width - 3)
+	clear(v.rendered)
+	v.frame = ""
+}
+
+func (v *view) filtering() bool {
+	return v.minLevel > clog.LevelTrace || v.filter.String() != ""
+}
+
+func (v *view) count() int {
+	if v.filtering() {
+		return len(v.visible)
+	}
+	return v.ix.count()
+}
+
+func (v *view) lineAt(pos int) int {
+	if v.filtering() {
+		return v.visible[pos]
+	}
+	return pos
+}
+
+// refilter starts over finding the lines to show, keeping the cursor near
+// the line it is on.
+func (v *view) refilter() {
+	if v.count() > 0 {
+		v.anchor = v.lineAt(v.cursor)
+	}
+	v.visible, v.scanned = v.visible[:0], 0
+	v.top, v.cursor = 0, 0
+}
+
+// refresh catches up with the lines indexed since the last frame. Filtering
+// is done in steps, so a large file does not hold up the keys.
+func (v *view) refresh() {
+	total, partial, resets := v.ix.state()
+	if resets != v.resets {
+		v.resets = resets
+		v.scanned, v.visible, v.top, v.cursor, v.anchor = 0, v.visible[:0], 0, 0, 0
+		clear(v.rendered)
+	}
+	v.partial = -1
+	if partial {
+		v.partial = total - 1
+	}
+	if v.filtering() {
+		// a partial line is filtered once it is complete
+		if partial {
+			total--
+		}
+		deadline := time.Now().Add(50 * time.Millisecond)
+		for v.scanned < total && time.Now().Before(deadline) {
+			n := min(total-v.scanned, 1024)
+			var lines [][]byte
+			if v.filter.String() != "" {
+				lines = v.ix.read(v.scanned, n)
+			}
+			for i := 0; i < n; i++ {
+				line := v.scanned + i
+				if !v.match(line, lines, i) {
+					continue
+				}
+				v.visible = append(v.visible, line)
+				if line <= v.anchor {
+					v.cursor = len(v.visible) - 1
+				}
+			}
+			v.scanned += n
+		}
+	}
+	if v.follow && v.count() > 0 {
+		v.cursor = v.count() - 1
+	}
+	v.scroll()
+}
+
+func (v *view) match(line int, lines [][]byte, i int) bool {
+	if v.minLevel > clog.LevelTrace && v.ix.level(line) < int8(v.minLevel) {
+		return false
+	}
+	if v.filter.String() == "" {
+		return true
+	}
+	if i >= len(lines) {
+		return false
+	}
+	e, err := v.logger.ParseLine(lines[i])
+	return err == nil && v.filter.Match(e)
+}
+
+func (v *view) expanded(line int) bool {
+	return v.expandAll != v.toggled[line]
+}
+
+// rows renders the entry at pos, one string per screen row.
+func (v *view) rows(pos int) []string {
+	line := v.lineAt(pos)
+	key := renderKey{line, v.expanded(line)}
+	if rows, ok := v.rendered[key]; ok {
+		return rows
+	}
+	if len(v.rendered) > 4096 {
+		clear(v.rendered)
+	}
+	var text []byte
+	if lines := v.ix.read(line, 1); len(lines) > 0 {
+		text = lines[0]
+	}
+	rows := v.render(text, key.expanded)
+	for i, row := range rows {
+		rows[i] = truncate.StringWithTail(row, uint(v.width-3), "…")
+	}
+	// a partial line is rendered again as it grows
+	if line != v.partial {
+		v.rendered[key] = rows
+	}
+	return rows
+}
+
+func (v *view) render(text []byte, expanded bool) []string {
+	e, err := v.logger.ParseLine(text)
+	if err != nil {
+		return []string{plainText(text)}
+	}
+	hidden := e.Fields.Len()
+	if !expanded {
+		for _, key := range e.Fields.Keys() {
+			e.Fields.Delete(key)
+		}
+		if e.Caller != "" {
+			hidden++
+			e.Caller = ""
+		}
+	}
+	v.out.Reset()
+	v.logger.Log(e)
+	rows := strings.Split(strings.TrimRight(v.out.String(), "\n"), "\n")
+	if !expanded {
+		rows = rows[:1]
+		if hidden > 0 {
+			rows[0] += hintStyle.Render(fmt.Sprintf("  +%d", hidden))
+		}
+	}
+	return rows
+}
+
+// scroll moves the top of the screen so the cursor's entry is on it.
+func (v *view) scroll() {
+	count, body := v.count(), v.height-1
+	v.cursor = max(min(v.cursor, count-1), 0)
+	if v.cursor < v.top {
+		v.top = v.cursor
+	}
+	// every entry takes a row at least
+	v.top = max(v.top, v.cursor-body+1)
+	for v.top < v.cursor {
+		rows := 0
+		for pos := v.top; pos <= v.cursor && rows <= body; pos++ {
+			rows += len(v.rows(pos))
+		}
+		if rows <= body {
+			break
+		}
+		v.top++
+	}
+}
+
+func (v *view) draw() {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	count, body, rows := v.count(), v.height-1, 0
+	v.shown = 0
+	for pos := v.top; pos < count && rows < body; pos++ {
+		gutter := "  "
+		if pos == v.cursor {
+			gutter = cursorStyle.Render("▌") + " "
+		}
+		for _, row := range v.rows(pos) {
+			if rows == body {
+				break
+			}
+			b.WriteString(gutter + row + "\x1b[K\r\n")
+			rows++
+		}
+		v.shown++
+	}
+	for ; rows < body; rows++ {
+		b.WriteString("\x1b[K\r\n")
+	}
+	b.WriteString(v.statusLine())
+
+	if frame := b.String(); frame != v.frame {
+		v.frame = frame
+		os.Stdout.WriteString(frame)
+	}
+}
+
+func (v *view) statusLine() string {
+	if v.prompt != "" {
+		line := v.prompt + string(v.input) + "█"
+		return truncate.String(line, uint(v.width-1)) + "\x1b[K"
+	}
+	parts := []string{v.name}
+	count := v.count()
+	parts = append(parts, fmt.Sprintf("%d/%d", min(v.cursor+1, count), count))
+	if v.minLevel > clog.LevelTrace {
+		parts = append(parts, "level>="+v.minLevel.String())
+	}
+	if v.filter.String() != "" {
+		parts = append(parts, "filter: "+v.filter.String())
+	}
+	if v.follow {
+		parts = append(parts, "follow")
+	}
+	if p := v.ix.progress(); p < 1 {
+		parts = append(parts, fmt.Sprintf("indexing %d%%", int(p*100)))
+	} else if v.filtering() && v.scanned < v.ix.count() {
+		parts = append(parts, "filtering")
+	}
+	left := " " + strings.Join(parts, "  ")
+	right := v.status
+	if right == "" {
+		right = "? help"
+	}
+	right += " "
+	gap := v.width - lipgloss.Width(left) - lipgloss.Width(right)
+	if gap < 1 {
+		return statusStyle.Render(truncate.String(left+" "+right, uint(v.width)))
+	}
+	return statusStyle.Render(left + strings.Repeat(" ", gap) + right)
+}
+
+// key handles a key press, and reports whether to keep going.
+func (v *view) key(key string) bool {
+	if v.prompt != "" {
+		v.edit(key)
+		return true
+	}
+	v.status = ""
+	page := max(v.shown-1, 1)
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "j", "down":
+		v.move(1)
+	case "k", "up":
+		v.move(-1)
+	case "pgdown", "ctrl-d":
+		v.move(page)
+	case "pgup", "ctrl-u":
+		v.move(-page)
+	case "g", "home":
+		v.follow = false
+		v.cursor = 0
+	case "G", "end":
+		v.cursor = v.count() - 1
+	case " ", "enter", "tab":
+		if v.count() > 0 {
+			line := v.lineAt(v.cursor)
+			v.toggled[line] = !v.toggled[line]
+		}
+	case "x":
+		v.expandAll = !v.expandAll
+		clear(v.toggled)
+	case "/", "&":
+		v.prompt, v.input = key, nil
+		if key == "&" {
+			v.input = []rune(v.filter.String())
+		}
+	case "n":
+		v.find(1)
+	case "N":
+		v.find(-1)
+	case "e":
+		v.findError(1)
+	case "E":
+		v.findError(-1)
+	case "l", "L":
+		step := 1
+		if key == "L" {
+			step = -1
+		}
+		v.minLevel = max(min(v.minLevel+clog.Level(step), clog.LevelFatal), clog.LevelTrace)
+		v.refilter()
+	case "f":
+		v.follow = !v.follow
+	case "esc":
+		v.search = nil
+	case "?":
+		v.status = viewHelp
+	}
+	v.scroll()
+	return true
+}
+
+func (v *view) move(n int) {
+	if n < 0 {
+		v.follow = false
+	}
+	v.cursor += n
+}
+
+// edit handles a key while a search or filter is typed.
+func (v *view) edit(key string) {
+	switch key {
+	case "esc", "ctrl-c":
+		v.prompt = ""
+	case "backspace":
+		if len(v.input) > 0 {
+			v.input = v.input[:len(v.input)-1]
+		}
+	case "enter":
+		prompt, text := v.prompt, string(v.input)
+		v.prompt = ""
+		if prompt == "&" {
+			filter, err := clog.ParseFilter(text)
+			if err != nil {
+				v.status = strings.TrimPrefix(err.Error(), "clog: ")
+				return
+			}
+			v.filter = filter
+			v.refilter()
+			return
+		}
+		if text == "" {
+			v.search = nil
+			return
+		}
+		// smart case: an expression without capitals ignores case
+		if strings.ToLower(text) == text {
+			text = "(?i)" + text
+		}
+		re, err := regexp.Compile(text)
+		if err != nil {
+			v.status = err.Error()
+			return
+		}
+		v.search = re
+		v.find(1)
+	default:
+		if len([]rune(key)) == 1 {
+			v.input = append(v.input, []rune(key)...)
+		}
+	}
+}
+
+// find moves the cursor to the next entry matching the search, in the
+// direction of step.
+func (v *view) find(step int) {
+	if v.search == nil {
+		v.status = "no search"
+		return
+	}
+	// lines are read in blocks around the positions searched
+	var block [][]byte
+	blockStart := -1
+	for pos := v.cursor + step; pos >= 0 && pos < v.count(); pos += step {
+		line := v.lineAt(pos)
+		if blockStart < 0 || line < blockStart || line >= blockStart+len(block) {
+			blockStart = line
+			if step < 0 {
+				blockStart = max(line-1023, 0)
+			}
+			block = v.ix.read(blockStart, 1024)
+			if line-blockStart >= len(block) {
+				break
+			}
+		}
+		if v.search.Match(block[line-blockStart]) {
+			v.follow = false
+			v.cursor = pos
+			return
+		}
+	}
+	v.status = "not found: " + v.search.String()
+}
+
+func (v *view) findError(step int) {
+	for pos := v.cursor + step; pos >= 0 && pos < v.count(); pos += step {
+		if v.ix.level(v.lineAt(pos)) >= int8(clog.LevelError) {
+			v.follow = false
+			v.cursor = pos
+			return
+		}
+	}
+	v.status = "no more errors"
+}
@@ -0,0 +1,40 @@
+package clog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompactLayout(t *testing.T) {
+	l, b := bufferLogger()
+	l.WithCompact(true).SetWidth(40)
+
+	l.Info().Any("user", "ann").Any("n", 3).Msg("saved")
+	if got, want := uncolored(b.String()), "• saved  user=ann  n=3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// entries that cannot fit on one line fall back to the tree
+	for _, e := range []*Entry{
+		l.Info().Any("user", map[string]int{"id": 1}),
+		l.Info().Any("note", strings.Repeat("x", 50)),
+	} {
+		b.Reset()
+		e.Msg("m")
+		if out := uncolored(b.String()); !strings.Contains(out, "└─") {
+			t.Errorf("expected a tree: %q", out)
+		}
+	}
+
+	b.Reset()
+	l.SetEscapeMode(AllowControl)
+	l.Info().Msg("two\nlines")
+	if out := uncolored(b.String()); out != "• two\n  lines\n" {
+		t.Errorf("multi-line message: %q", out)
+	}
+	b.Reset()
+	l.Info().Any("note", "a\nb").Msg("m")
+	if out := uncolored(b.String()); out != "• m\n  └─ note: a\n           b\n" {
+		t.Errorf("multi-line value: %q", out)
+	}
+}
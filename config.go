@@ -0,0 +1,661 @@
+package clog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config is the setup of a logger as read from a file by LoadConfig. Levels,
+// filters and durations are kept as written, so that Validate can name the
+// setting at fault.
+type Config struct {
+	Level string `json:"level"`
+	// Components sets the level of the entries whose component field has
+	// one of the names.
+	Components map[string]string `json:"components"`
+	// Format is pretty, json or logfmt, and Output stderr, stdout or the
+	// path of a file.
+	Format     string          `json:"format"`
+	Output     string          `json:"output"`
+	Rotation   *RotationConfig `json:"rotation"`
+	Time       bool            `json:"time"`
+	TimeFormat string          `json:"time_format"`
+	Caller     bool            `json:"caller"`
+	LevelText  bool            `json:"level_text"`
+	Compact    bool            `json:"compact"`
+	// Color is auto, always or never.
+	Color  string        `json:"color"`
+	Theme  string        `json:"theme"`
+	Filter string        `json:"filter"`
+	Redact *RedactConfig `json:"redact"`
+	Sinks  []SinkConfig  `json:"sinks"`
+	// Watch reloads the levels and filters when the file changes.
+	Watch bool `json:"watch"`
+}
+
+// RotationConfig sets up a RotatingFile.
+type RotationConfig struct {
+	MaxSizeMB  int `json:"max_size_mb"`
+	MaxBackups int `json:"max_backups"`
+	MaxAgeDays int `json:"max_age_days"`
+}
+
+type RedactConfig struct {
+	Keys     []string `json:"keys"`
+	Patterns []string `json:"patterns"`
+	// Scrub adds DefaultScrubbers, and NoDefaults leaves out
+	// DefaultRedactKeys.
+	Scrub      bool `json:"scrub"`
+	NoDefaults bool `json:"no_defaults"`
+}
+
+// SinkConfig is a sink of Type file, syslog, journald, otlp, loki,
+// elasticsearch, webhook or ship. Its Level and Filter apply on top of the
+// logger's, to this sink alone; webhook sinks default to the error level.
+type SinkConfig struct {
+	Type   string `json:"type"`
+	Level  string `json:"level"`
+	Filter string `json:"filter"`
+	// URL is the endpoint of otlp, loki, elasticsearch and webhook sinks.
+	URL string `json:"url"`
+	// Address is the syslog daemon or ship collector, and Network the
+	// syslog network: unix, udp or tcp.
+	Address string `json:"address"`
+	Network string `json:"network"`
+	// Path is the file of file sinks and the socket of journald sinks.
+	Path     string          `json:"path"`
+	Rotation *RotationConfig `json:"rotation"`
+	// Format is json or logfmt for file sinks, rfc5424 or rfc3164 for
+	// syslog sinks.
+	Format   string `json:"format"`
+	Facility string `json:"facility"`
+	AppName  string `json:"app_name"`
+	// Preset is the message layout of webhook sinks: slack, mattermost,
+	// discord or json.
+	Preset   string `json:"preset"`
+	SpoolDir string `json:"spool_dir"`
+	TLS      bool   `json:"tls"`
+	Index    string `json:"index"`
+	TenantID string `json:"tenant_id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	APIKey   string `json:"api_key"`
+	// Headers are added to the requests of HTTP sinks.
+	Headers       map[string]string `json:"headers"`
+	Protobuf      bool              `json:"protobuf"`
+	BatchSize     int               `json:"batch_size"`
+	BatchInterval string            `json:"batch_interval"`
+}
+
+var (
+	configFormats  = []string{"pretty", "json", "logfmt"}
+	configColors   = []string{"auto", "always", "never"}
+	syslogFormats  = map[string]SyslogFormat{"rfc5424": RFC5424, "rfc3164": RFC3164}
+	webhookPresets = map[string]WebhookPreset{
+		"slack":      SlackWebhook,
+		"mattermost": MattermostWebhook,
+		"discord":    DiscordWebhook,
+		"json":       JSONWebhook,
+	}
+	facilities = map[string]Facility{
+		"kern": FacilityKern, "user": FacilityUser, "daemon": FacilityDaemon, "auth": FacilityAuth,
+		"local0": FacilityLocal0, "local1": FacilityLocal1, "local2": FacilityLocal2, "local3": FacilityLocal3,
+		"local4": FacilityLocal4, "local5": FacilityLocal5, "local6": FacilityLocal6, "local7": FacilityLocal7,
+	}
+)
+
+// configPollInterval is how often a watched config file is checked for
+// changes.
+const configPollInterval = time.Second
+
+// LoadConfig builds a logger from the JSON or TOML file at path, told apart
+// by their extension. Its level, component levels and filter are kept with
+// the config rather than in the Level, Components and Filter fields, and
+// shared with the task and child loggers derived from it. With watch set in
+// the file, changes to them are applied to all of those loggers as the file
+// is saved; Close stops watching.
+func LoadConfig(path string) (*Logger, error) {
+	c, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.validate(path); err != nil {
+		return nil, err
+	}
+	l, state, err := c.build()
+	if err != nil {
+		return nil, fmt.Errorf("clog: %s: %w", path, err)
+	}
+	state.path, state.config = path, c
+	if c.Watch {
+		info, err := os.Stat(path)
+		if err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("clog: %w", err)
+		}
+		state.watch(l, info)
+	}
+	return l, nil
+}
+
+// ReadConfig reads the JSON or TOML file at path without validating it.
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("clog: %w", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".toml":
+		doc, err := parseTOML(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("clog: %s: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("clog: %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("clog: %s: unknown config format %q, use .json or .toml", path, ext)
+	}
+
+	c := &Config{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(c); err != nil {
+		return nil, fmt.Errorf("clog: %s: %s", path, decodeError(data, err))
+	}
+	if d.More() {
+		return nil, fmt.Errorf("clog: %s: unexpected data after the config", path)
+	}
+	return c, nil
+}
+
+// decodeError rewords JSON errors in terms of the config file.
+func decodeError(data []byte, err error) string {
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax):
+		line := bytes.Count(data[:syntax.Offset], []byte("\n")) + 1
+		return fmt.Sprintf("line %d: %s", line, syntax)
+	case errors.As(err, &typ):
+		return fmt.Sprintf("%s: expected %s, got %s", typ.Field, typ.Type, typ.Value)
+	}
+	return strings.TrimPrefix(err.Error(), "json: ")
+}
+
+// Validate checks the whole config, returning every problem found.
+func (c *Config) Validate() error {
+	return c.validate("")
+}
+
+func (c *Config) validate(file string) error {
+	check := &configCheck{file: file}
+	c.checkLevels(check)
+	check.oneOf("format", c.Format, configFormats)
+	check.oneOf("color", c.Color, configColors)
+	check.rotation("rotation", c.Rotation)
+	if c.Rotation != nil && (c.Output == "" || c.Output == "stderr" || c.Output == "stdout") {
+		check.fail("rotation", "takes a file output")
+	}
+	if _, ok := Themes[c.Theme]; c.Theme != "" && !ok {
+		check.fail("theme", "unknown theme %q", c.Theme)
+	}
+	check.redactor("redact", c.Redact)
+
+	for i, s := range c.Sinks {
+		path := fmt.Sprintf("sinks[%d]", i)
+		require := func(field, value string) {
+			if value == "" {
+				check.fail(path+"."+field, "is required for %s sinks", s.Type)
+			}
+		}
+		check.duration(path+".batch_interval", s.BatchInterval)
+		if s.BatchSize < 0 {
+			check.fail(path+".batch_size", "must not be negative")
+		}
+		switch s.Type {
+		case "file":
+			require("path", s.Path)
+			check.oneOf(path+".format", s.Format, []string{"json", "logfmt"})
+			check.rotation(path+".rotation", s.Rotation)
+		case "syslog":
+			check.oneOf(path+".network", s.Network, []string{"unix", "unixgram", "udp", "tcp"})
+			if _, ok := syslogFormats[s.Format]; s.Format != "" && !ok {
+				check.fail(path+".format", "must be rfc5424 or rfc3164")
+			}
+			if _, ok := facilities[s.Facility]; s.Facility != "" && !ok {
+				check.fail(path+".facility", "unknown facility %q", s.Facility)
+			}
+		case "journald":
+		case "otlp":
+		case "loki", "elasticsearch":
+			require("url", s.URL)
+		case "webhook":
+			require("url", s.URL)
+			if _, ok := webhookPresets[s.Preset]; s.Preset != "" && !ok {
+				check.fail(path+".preset", "must be slack, mattermost, discord or json")
+			}
+		case "ship":
+			require("address", s.Address)
+		case "":
+			check.fail(path+".type", "is required")
+		default:
+			check.fail(path+".type", "unknown sink type %q", s.Type)
+		}
+	}
+	return errors.Join(check.errs...)
+}
+
+// checkLevels checks the settings that a watched file reloads.
+func (c *Config) checkLevels(check *configCheck) {
+	check.level("level", c.Level)
+	for component, level := range c.Components {
+		check.level("components."+component, level)
+	}
+	check.filter("filter", c.Filter)
+	for i, s := range c.Sinks {
+		check.level(fmt.Sprintf("sinks[%d].level", i), s.Level)
+		check.filter(fmt.Sprintf("sinks[%d].filter", i), s.Filter)
+	}
+}
+
+// build makes the logger of a valid config, opening its output and sinks.
+func (c *Config) build() (*Logger, *configState, error) {
+	check := &configCheck{}
+	state := &configState{}
+	l := New()
+	l.config = state
+
+	switch c.Output {
+	case "", "stderr":
+	case "stdout":
+		l.Writer = os.Stdout
+	default:
+		file := check.rotatingFile(c.Output, c.Rotation)
+		l.Writer, state.output = file, file
+	}
+	switch c.Format {
+	case "json":
+		l.Formatter = &JSONFormatter{TimeFormat: c.TimeFormat}
+	case "logfmt":
+		l.Formatter = &LogfmtFormatter{TimeFormat: c.TimeFormat}
+	}
+	switch c.Color {
+	case "", "auto":
+		l.NoColor = l.NoColor || !isTerminal(l.Writer)
+	case "always":
+		l.NoColor = false
+	case "never":
+		l.NoColor = true
+	}
+	if c.TimeFormat != "" {
+		l.TimeFormat = c.TimeFormat
+	}
+	l.ShowTime, l.ShowCaller, l.ShowLevelText, l.Compact = c.Time, c.Caller, c.LevelText, c.Compact
+	if theme, ok := Themes[c.Theme]; ok {
+		l.Theme = &theme
+	}
+	if c.Redact != nil {
+		l.Redactor = check.redactor("redact", c.Redact)
+	}
+
+	for i, s := range c.Sinks {
+		sink, err := c.openSink(check, s)
+		if err != nil {
+			_ = l.Close()
+			return nil, nil, fmt.Errorf("sinks[%d]: %w", i, err)
+		}
+		filtered := &FilterSink{Sink: sink}
+		state.sinks = append(state.sinks, filtered)
+		l.AddSink(filtered)
+	}
+	c.applyLevels(l, state)
+	return l, state, nil
+}
+
+func (c *Config) openSink(check *configCheck, s SinkConfig) (Sink, error) {
+	interval := check.duration("", s.BatchInterval)
+	batching := func(b *Batching) {
+		if s.BatchSize > 0 {
+			b.BatchSize = s.BatchSize
+		}
+		if interval > 0 {
+			b.BatchInterval = interval
+		}
+	}
+	headers := func(h map[string]string) map[string]string {
+		if len(s.Headers) > 0 {
+			return s.Headers
+		}
+		return h
+	}
+
+	switch s.Type {
+	case "file":
+		var formatter Formatter = &JSONFormatter{}
+		if s.Format == "logfmt" {
+			formatter = &LogfmtFormatter{}
+		}
+		return &WriterSink{Writer: check.rotatingFile(s.Path, s.Rotation), Formatter: formatter}, nil
+	case "syslog":
+		sink, err := DialSyslog(s.Network, s.Address)
+		if err != nil {
+			return nil, err
+		}
+		batching(&sink.Batching)
+		sink.Format = syslogFormats[s.Format]
+		if s.Facility != "" {
+			sink.Facility = facilities[s.Facility]
+		}
+		if s.AppName != "" {
+			sink.AppName = s.AppName
+		}
+		return sink, nil
+	case "journald":
+		sink, err := DialJournal(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		if s.AppName != "" {
+			sink.Identifier = s.AppName
+		}
+		return sink, nil
+	case "otlp":
+		sink := NewOTLPExporter(s.URL)
+		batching(&sink.Batching)
+		sink.Protobuf, sink.Headers = s.Protobuf, headers(sink.Headers)
+		return sink, nil
+	case "loki":
+		sink := NewLokiSink(s.URL)
+		batching(&sink.Batching)
+		sink.Protobuf, sink.Headers = s.Protobuf, headers(sink.Headers)
+		if s.TenantID != "" {
+			sink.TenantID = s.TenantID
+		}
+		return sink, nil
+	case "elasticsearch":
+		sink := NewElasticsearchSink(s.URL)
+		batching(&sink.Batching)
+		sink.Headers = headers(sink.Headers)
+		sink.Username, sink.Password, sink.APIKey = s.Username, s.Password, s.APIKey
+		if s.Index != "" {
+			sink.Index = s.Index
+		}
+		return sink, nil
+	case "webhook":
+		preset := SlackWebhook
+		if s.Preset != "" {
+			preset = webhookPresets[s.Preset]
+		}
+		sink := NewWebhookSink(s.URL, preset)
+		batching(&sink.Batching)
+		sink.Headers = headers(sink.Headers)
+		// the level is left to the filter, so that a reload can change it
+		sink.Level = LevelTrace
+		return sink, nil
+	case "ship":
+		sink := NewShipSink(s.Address, s.SpoolDir)
+		if s.TLS {
+			host, _, _ := strings.Cut(s.Address, ":")
+			sink.TLSConfig = &tls.Config{ServerName: host}
+		}
+		return sink, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q", s.Type)
+}
+
+// applyLevels sets the levels and filters of l, the loggers derived from it
+// and its sinks from a valid config. The sinks are called with the lock
+// held, so it guards them.
+func (c *Config) applyLevels(l *Logger, state *configState) {
+	check := &configCheck{}
+	level := check.level("", c.Level)
+	components := map[string]Level{}
+	for component, text := range c.Components {
+		components[component] = check.level("", text)
+	}
+	filter := check.filter("", c.Filter)
+	state.levels.Store(&levelSettings{level: level, components: components, filter: filter})
+
+	l.lock()
+	defer l.unlock()
+	for i, sink := range state.sinks {
+		sink.Filter = check.sinkFilter(c.Sinks[i])
+	}
+}
+
+// reloadable returns c without the settings a watched file reloads, to tell
+// whether anything else changed.
+func (c *Config) reloadable() Config {
+	r := *c
+	r.Level, r.Components, r.Filter, r.Watch = "", nil, "", false
+	r.Sinks = append([]SinkConfig(nil), c.Sinks...)
+	for i := range r.Sinks {
+		r.Sinks[i].Level, r.Sinks[i].Filter = "", ""
+	}
+	return r
+}
+
+// configCheck collects the problems of a config, with the path of the
+// setting each is about. Its methods return the value of a setting, or its
+// zero value when invalid.
+type configCheck struct {
+	file string
+	errs []error
+}
+
+func (c *configCheck) fail(path, format string, args ...any) {
+	err := fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...))
+	if c.file != "" {
+		err = fmt.Errorf("clog: %s: %w", c.file, err)
+	}
+	c.errs = append(c.errs, err)
+}
+
+func (c *configCheck) oneOf(path, value string, options []string) {
+	for _, option := range options {
+		if value == option {
+			return
+		}
+	}
+	if value != "" {
+		c.fail(path, "must be one of %s, not %q", strings.Join(options, ", "), value)
+	}
+}
+
+// level returns the level named text, or LevelInfo when text is empty.
+func (c *configCheck) level(path, text string) Level {
+	if text == "" {
+		return LevelInfo
+	}
+	level, err := ParseLevel(text)
+	if err != nil {
+		c.fail(path, "unknown level %q", text)
+	}
+	return level
+}
+
+func (c *configCheck) filter(path, text string) *Filter {
+	if text == "" {
+		return nil
+	}
+	filter, err := ParseFilter(text)
+	if err != nil {
+		c.fail(path, "%s", strings.TrimPrefix(err.Error(), "clog: "))
+	}
+	return filter
+}
+
+// sinkFilter combines the level and filter of a sink into one filter.
+func (c *configCheck) sinkFilter(s SinkConfig) *Filter {
+	var terms []string
+	if s.Level == "" && s.Type == "webhook" {
+		s.Level = "error"
+	}
+	if s.Level != "" {
+		terms = append(terms, "level>="+c.level("", s.Level).String())
+	}
+	if s.Filter != "" {
+		terms = append(terms, "("+s.Filter+")")
+	}
+	return c.filter("", strings.Join(terms, " and "))
+}
+
+func (c *configCheck) duration(path, text string) time.Duration {
+	if text == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil || d < 0 {
+		c.fail(path, "invalid duration %q, such as 500ms or 10s", text)
+	}
+	return d
+}
+
+func (c *configCheck) rotation(path string, r *RotationConfig) {
+	if r == nil {
+		return
+	}
+	if r.MaxSizeMB < 0 {
+		c.fail(path+".max_size_mb", "must not be negative")
+	}
+	if r.MaxBackups < 0 {
+		c.fail(path+".max_backups", "must not be negative")
+	}
+	if r.MaxAgeDays < 0 {
+		c.fail(path+".max_age_days", "must not be negative")
+	}
+}
+
+func (c *configCheck) rotatingFile(path string, r *RotationConfig) *RotatingFile {
+	f := &RotatingFile{Path: path}
+	if r != nil {
+		f.MaxSize = int64(r.MaxSizeMB) << 20
+		f.MaxBackups = r.MaxBackups
+		f.MaxAge = time.Duration(r.MaxAgeDays) * 24 * time.Hour
+	}
+	return f
+}
+
+func (c *configCheck) redactor(path string, r *RedactConfig) *Redactor {
+	if r == nil {
+		return nil
+	}
+	redactor := NewRedactor()
+	if r.NoDefaults {
+		redactor.Keys = nil
+	}
+	if r.Scrub {
+		redactor.AddScrubbers(DefaultScrubbers...)
+	}
+	redactor.AddKeys(r.Keys...)
+	for i, pattern := range r.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			c.fail(fmt.Sprintf("%s.patterns[%d]", path, i), "%s", err)
+			continue
+		}
+		redactor.AddScrubbers(RegexpScrubber(pattern))
+	}
+	return redactor
+}
+
+// configState is what a logger built by LoadConfig keeps of its config:
+// the files it opened, the level settings shared with the loggers derived
+// from it, and the sinks whose filters a reload replaces.
+type configState struct {
+	path   string
+	config *Config
+	output io.Closer
+	levels atomic.Pointer[levelSettings]
+	sinks  []*FilterSink
+	once   sync.Once
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// update changes a copy of the level settings and stores it.
+func (s *configState) update(change func(*levelSettings)) {
+	for {
+		old := s.levels.Load()
+		var settings levelSettings
+		if old != nil {
+			settings = *old
+		}
+		change(&settings)
+		if s.levels.CompareAndSwap(old, &settings) {
+			return
+		}
+	}
+}
+
+// watch polls the config file, reloading it when its size or time changes.
+func (s *configState) watch(l *Logger, info os.FileInfo) {
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+			latest, err := os.Stat(s.path)
+			if err != nil || latest.ModTime().Equal(info.ModTime()) && latest.Size() == info.Size() {
+				continue
+			}
+			info = latest
+			s.reload(l)
+		}
+	}()
+}
+
+// reload applies the levels and filters of the config file to l. A config
+// that does not load is reported and leaves l as it was.
+func (s *configState) reload(l *Logger) {
+	c, err := ReadConfig(s.path)
+	if err == nil {
+		err = c.validate(s.path)
+	}
+	if err != nil {
+		l.Error().Err(err).Msg("config not reloaded")
+		return
+	}
+	if len(c.Sinks) != len(s.config.Sinks) {
+		l.Warn().Any("path", s.path).Msg("config not reloaded: the sinks changed, which takes a restart")
+		return
+	}
+	if !reflect.DeepEqual(c.reloadable(), s.config.reloadable()) {
+		l.Warn().Any("path", s.path).Msg("config changes other than levels and filters take a restart")
+	}
+	c.applyLevels(l, s)
+	s.config = c
+	l.Info().Any("path", s.path).Msg("config reloaded")
+}
+
+func (s *configState) close() error {
+	s.once.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+	if s.output != nil {
+		return s.output.Close()
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package clog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, text string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigReloadReachesDerivedLoggers(t *testing.T) {
+	dir := t.TempDir()
+	path, output := filepath.Join(dir, "clog.toml"), filepath.Join(dir, "out.log")
+	writeConfig(t, path, "level = \"info\"\nformat = \"logfmt\"\noutput = \""+filepath.ToSlash(output)+"\"\n")
+
+	l, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	child := l.Child()
+	task := l.Task("build")
+
+	child.Debug().Msg("hidden")
+	writeConfig(t, path, "level = \"debug\"\nformat = \"logfmt\"\noutput = \""+filepath.ToSlash(output)+"\"\n"+
+		"filter = \"not drop\"\n[components]\ndb = \"error\"\n")
+
+	// log from the derived loggers while the config is reloaded
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			child.Debug().Msg("busy")
+		}
+	}()
+	l.config.reload(l)
+	wg.Wait()
+
+	child.Debug().Msg("child debug")
+	task.Debug().Msg("task debug")
+	child.Debug().Any("drop", true).Msg("filtered")
+	child.Warn().Any("component", "db").Msg("quiet component")
+	l.Child().Debug().Msg("new child debug")
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, msg := range []string{"child debug", "task debug", "new child debug", "config reloaded"} {
+		if !strings.Contains(out, msg) {
+			t.Errorf("missing %q in\n%s", msg, out)
+		}
+	}
+	for _, msg := range []string{"hidden", "filtered", "quiet component"} {
+		if strings.Contains(out, msg) {
+			t.Errorf("unexpected %q in\n%s", msg, out)
+		}
+	}
+}
+
+func TestConfigSetLogLevel(t *testing.T) {
+	dir := t.TempDir()
+	path, output := filepath.Join(dir, "clog.json"), filepath.Join(dir, "out.log")
+	writeConfig(t, path, `{"level": "warn", "format": "logfmt", "output": "`+filepath.ToSlash(output)+`"}`)
+
+	l, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := l.Child()
+	l.SetLogLevel(LevelDebug)
+	child.Debug().Msg("shared level")
+	l.Close()
+
+	data, _ := os.ReadFile(output)
+	if !strings.Contains(string(data), "shared level") {
+		t.Errorf("got %q", data)
+	}
+}
+
+func TestConfigTheme(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clog.toml")
+	writeConfig(t, path, "theme = \"mono\"\noutput = \""+filepath.ToSlash(filepath.Join(dir, "out.log"))+"\"\n")
+
+	before := Styles
+	l, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if l.Theme == nil || !reflect.DeepEqual(*l.Theme, Themes["mono"]) {
+		t.Errorf("logger theme %+v", l.Theme)
+	}
+	if !reflect.DeepEqual(Styles, before) {
+		t.Error("config changed the package styles")
+	}
+
+	err = (&Config{Theme: "nope"}).Validate()
+	if err == nil || !strings.Contains(err.Error(), "theme") {
+		t.Errorf("unknown theme: %v", err)
+	}
+}
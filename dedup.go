@@ -0,0 +1,129 @@
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+const KindRepeat = "repeat"
+
+// Deduplicator collapses consecutive identical entries: same level, task,
+// message and fields, leaving out the fields matching Ignore. On a terminal
+// the last entry is rewritten with a repeat counter. Elsewhere the repeats
+// are dropped and reported by a single line once a different entry is
+// logged, the logger is closed or Window has passed since the first repeat.
+//
+// A Deduplicator is shared by a logger and its clones and guarded by their
+// common lock, it must not be set on unrelated loggers.
+type Deduplicator struct {
+	Ignore []string
+	Window time.Duration
+
+	last    string
+	entry   *Entry
+	repeats int
+	lines   int
+	timer   *time.Timer
+}
+
+func NewDeduplicator(ignore ...string) *Deduplicator {
+	return &Deduplicator{
+		Ignore: ignore,
+		Window: 10 * time.Second,
+	}
+}
+
+func SetDeduplicator(dedup *Deduplicator) *Logger {
+	return logger.SetDeduplicator(dedup)
+}
+
+func (l *Logger) SetDeduplicator(dedup *Deduplicator) *Logger {
+	l.Deduplicator = dedup
+	return l
+}
+
+func (d *Deduplicator) ignored(key string) bool {
+	for _, pattern := range d.Ignore {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Deduplicator) fingerprint(e *Entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\x00%q", e.Level, e.Message)
+	if e.Task != nil {
+		fmt.Fprintf(&b, "\x00%d", e.Task.ID())
+	}
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		if !d.ignored(it.Key) {
+			fmt.Fprintf(&b, "\x00%q=%v", it.Key, it.Value)
+		}
+	}
+	return b.String()
+}
+
+// write logs e unless it repeats the previous entry; the lock must be held.
+func (d *Deduplicator) write(l *Logger, e *Entry) {
+	fingerprint := d.fingerprint(e)
+	if d.entry != nil && fingerprint == d.last &&
+		(d.entry.Logger == l || sameWriter(d.entry.Logger.Writer, l.Writer)) {
+		d.repeats++
+		if l.live() {
+			e.Repeated = d.repeats + 1
+			p := l.formatter().Format(e)
+			l.rewrite(d.lines, p)
+			d.lines = bytes.Count(p, []byte("\n"))
+			return
+		}
+		if d.timer == nil && d.Window > 0 {
+			d.timer = time.AfterFunc(d.Window, func() {
+				l.lock()
+				defer l.unlock()
+				d.flush()
+			})
+		}
+		return
+	}
+
+	d.flush()
+	p := l.formatter().Format(e)
+	l.write(p)
+	d.last, d.entry, d.repeats = fingerprint, e, 0
+	d.lines = bytes.Count(p, []byte("\n"))
+}
+
+// flush reports the repeats dropped since the last line; the lock must be held.
+func (d *Deduplicator) flush() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.entry == nil || d.repeats == 0 || d.entry.Logger.live() {
+		return
+	}
+	l := d.entry.Logger
+	e := l.newEntry(d.entry.Level)
+	e.Time = time.Now()
+	e.Task = d.entry.Task
+	e.Kind = KindRepeat
+	e.Repeated = d.repeats
+	e.Message = "repeated " + plural(d.repeats, "time")
+	l.write(l.formatter().Format(e))
+	// later repeats are reported by a line of their own
+	d.repeats = 0
+}
+
+func (l *Logger) flushRepeats() {
+	if l.Deduplicator == nil {
+		return
+	}
+	l.lock()
+	defer l.unlock()
+	l.Deduplicator.flush()
+}
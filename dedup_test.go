@@ -0,0 +1,77 @@
+package clog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeduplicator(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetDeduplicator(NewDeduplicator("attempt"))
+	for i := 0; i < 3; i++ {
+		l.Warn().Any("host", "db").Any("attempt", i).Msg("retrying")
+	}
+	l.Warn().Any("host", "cache").Msg("retrying")
+	l.Info().Msg("done")
+	l.Info().Msg("done")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `⚠ retrying
+  ├─ host: db
+  └─ attempt: 0
+⚠ repeated 2 times
+⚠ retrying
+  └─ host: cache
+• done
+• repeated 1 time
+`
+	if got := uncolored(b.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDeduplicatorWindow(t *testing.T) {
+	l, b := bufferLogger()
+	dedup := NewDeduplicator()
+	dedup.Window = 10 * time.Millisecond
+	l.SetDeduplicator(dedup)
+	l.Info().Msg("tick")
+	l.Info().Msg("tick")
+	time.Sleep(50 * time.Millisecond)
+	l.Info().Msg("tick")
+	l.Close()
+
+	if out := uncolored(b.String()); out != "• tick\n• repeated 1 time\n• repeated 1 time\n" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestDeduplicatorTasks(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetDeduplicator(NewDeduplicator())
+	a, c := l.Task("a"), l.Task("c")
+	a.Info().Msg("same")
+	c.Info().Msg("same")
+	if got := strings.Count(uncolored(b.String()), "same"); got != 2 {
+		t.Errorf("entries of different tasks collapsed: %q", b.String())
+	}
+}
+
+func TestDeduplicatorLive(t *testing.T) {
+	term := newTestTerminal(t, 80)
+	l := New().SetWriter(term).SetDeduplicator(NewDeduplicator())
+	s := l.Spinner("busy")
+	for i := 0; i < 3; i++ {
+		l.Info().Msg("poll")
+	}
+	s.Stop()
+	l.Close()
+
+	out := uncolored(term.String())
+	if !strings.Contains(out, "• poll (x3)") || strings.Contains(out, "repeated") {
+		t.Errorf("got %q", out)
+	}
+}
@@ -0,0 +1,232 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ECSVersion is the Elastic Common Schema version documents are written in.
+const ECSVersion = "8.11.0"
+
+// ElasticsearchSink indexes entries in Elasticsearch or OpenSearch through
+// the _bulk API, as Elastic Common Schema documents in a daily index named
+// after Index and the entry's date. Fields go under Namespace, apart from
+// the ECS fields. Items the cluster rejects with 429 or a 5xx status are
+// retried on their own.
+type ElasticsearchSink struct {
+	Batching
+	URL        string
+	Index      string
+	DateFormat string
+	Namespace  string
+	Username   string
+	Password   string
+	APIKey     string
+	Headers    map[string]string
+	Client     *http.Client
+
+	batcher batcher[*esDocument]
+}
+
+type esDocument struct {
+	index  string
+	source []byte
+	done   bool
+}
+
+func NewElasticsearchSink(url string) *ElasticsearchSink {
+	return &ElasticsearchSink{
+		Batching:   defaultBatching(),
+		URL:        strings.TrimRight(url, "/"),
+		Index:      "clog",
+		DateFormat: "2006.01.02",
+		Namespace:  "clog",
+		Client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *ElasticsearchSink) WriteEntry(e *Entry) error {
+	l := e.Logger
+	doc := Object{
+		{Key: "@timestamp", Value: e.Time.UTC().Format(time.RFC3339Nano)},
+		{Key: "ecs", Value: Object{{Key: "version", Value: ECSVersion}}},
+		{Key: "message", Value: e.Message},
+	}
+
+	logObj := Object{{Key: "level", Value: e.Level.String()}}
+	if i := strings.LastIndexByte(e.Caller, ':'); i >= 0 {
+		file := Object{{Key: "name", Value: e.Caller[:i]}}
+		if line, err := strconv.Atoi(e.Caller[i+1:]); err == nil {
+			file.Add("line", line)
+		}
+		logObj.Add("origin", Object{{Key: "file", Value: file}})
+	}
+	doc.Add("log", logObj)
+
+	if e.Error != nil {
+		scrub := func(s string) string {
+			if l.Redactor == nil {
+				return s
+			}
+			return l.Redactor.Scrub(s)
+		}
+		errObj := Object{{Key: "message", Value: scrub(l.sanitize(e.Error.Error()))}}
+		// errors that carry a stack trace print it with %+v
+		if stack := fmt.Sprintf("%+v", e.Error); stack != e.Error.Error() {
+			errObj.Add("stack_trace", scrub(stack))
+		}
+		doc.Add("error", errObj)
+	}
+	if e.Elapsed > 0 {
+		doc.Add("event", Object{{Key: "duration", Value: e.Elapsed.Nanoseconds()}})
+	}
+
+	var fields Object
+	for _, arg := range eventFields(e) {
+		fields.Add(arg.Key, arg.Value)
+	}
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		if it.Key == "err" && e.Error != nil {
+			continue
+		}
+		fields.Add(l.sanitize(it.Key), l.jsonValue(it.Value))
+	}
+	if len(fields) > 0 {
+		if s.Namespace == "" {
+			doc = append(doc, fields...)
+		} else {
+			doc.Add(s.Namespace, fields)
+		}
+	}
+
+	source, err := marshalJSON(doc)
+	if err != nil {
+		return err
+	}
+	index := s.Index
+	if s.DateFormat != "" {
+		index += "-" + e.Time.UTC().Format(s.DateFormat)
+	}
+	s.batcher.add(s.Batching, &esDocument{index: index, source: source}, s.send)
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (s *ElasticsearchSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// Close indexes the queued entries and stops the sink.
+func (s *ElasticsearchSink) Close() error {
+	s.batcher.close()
+	return nil
+}
+
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// send indexes the documents not yet done. Documents that failed for good
+// are marked done as well.
+func (s *ElasticsearchSink) send(docs []*esDocument) error {
+	var pending []*esDocument
+	var body bytes.Buffer
+	for _, doc := range docs {
+		if doc.done {
+			continue
+		}
+		pending = append(pending, doc)
+		action, _ := marshalJSON(map[string]any{"create": map[string]string{"_index": doc.index}})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc.source)
+		body.WriteByte('\n')
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/_bulk", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for key, value := range s.Headers {
+		req.Header.Set(key, value)
+	}
+	switch {
+	case s.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.APIKey)
+	case s.Username != "":
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(s.Username+":"+s.Password)))
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &retryError{err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		err := fmt.Errorf("clog: %s/_bulk: %s", s.URL, resp.Status)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			after, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			return &retryError{err: err, after: time.Duration(after) * time.Second}
+		}
+		return err
+	}
+
+	var result esBulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	var retryable, failed []error
+	for i, doc := range pending {
+		doc.done = true
+		if i >= len(result.Items) {
+			continue
+		}
+		for _, item := range result.Items[i] {
+			if item.Status < 300 {
+				continue
+			}
+			err := fmt.Errorf("%d %s: %s", item.Status, item.Error.Type, item.Error.Reason)
+			if item.Status == http.StatusTooManyRequests || item.Status >= 500 {
+				doc.done = false
+				retryable = append(retryable, err)
+			} else {
+				failed = append(failed, err)
+			}
+		}
+	}
+	var failure error
+	if len(failed) > 0 {
+		failure = fmt.Errorf("clog: %d of %d bulk items failed: %w", len(failed), len(pending), errors.Join(failed...))
+	}
+	if len(retryable) > 0 {
+		// the items that failed for good are not sent again, so they are
+		// reported now
+		if failure != nil && s.OnError != nil {
+			s.OnError(failure)
+		}
+		return &retryError{err: fmt.Errorf("clog: %d of %d bulk items rejected: %w", len(retryable), len(pending), errors.Join(retryable...))}
+	}
+	return failure
+}
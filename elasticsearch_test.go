@@ -0,0 +1,174 @@
+package clog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type bulkItem struct {
+	index  string
+	source map[string]any
+}
+
+// fakeBulk is an _bulk endpoint that answers every create action with the
+// status returned by status, given the message of the document and how many
+// times it was received.
+type fakeBulk struct {
+	mu       sync.Mutex
+	requests [][]bulkItem
+	header   http.Header
+	seen     map[string]int
+	status   func(message string, attempt int) int
+}
+
+func (f *fakeBulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	f.header = r.Header
+	var items []bulkItem
+	var statuses []string
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+			http.Error(w, "bad action", http.StatusBadRequest)
+			return
+		}
+		item := bulkItem{index: action["create"]["_index"]}
+		if err := json.Unmarshal(scanner.Bytes(), &item.source); err != nil {
+			http.Error(w, "bad source", http.StatusBadRequest)
+			return
+		}
+		items = append(items, item)
+
+		message, _ := item.source["message"].(string)
+		f.seen[message]++
+		status := http.StatusCreated
+		if f.status != nil {
+			status = f.status(message, f.seen[message])
+		}
+		statuses = append(statuses, fmt.Sprintf(
+			`{"create": {"status": %d, "error": {"type": "t", "reason": %q}}}`, status, message))
+	}
+	f.requests = append(f.requests, items)
+	fmt.Fprintf(w, `{"errors": true, "items": [%s]}`, strings.Join(statuses, ","))
+}
+
+func TestElasticsearchDocument(t *testing.T) {
+	bulk := &fakeBulk{seen: map[string]int{}}
+	srv := httptest.NewServer(bulk)
+	defer srv.Close()
+
+	s := NewElasticsearchSink(srv.URL + "/")
+	s.APIKey = "key"
+	e := testEntry(LevelError, "failed")
+	e.Caller = "main.go:7"
+	e.Elapsed = time.Second
+	e.Err(errors.New("timeout")).Any("user", "ann")
+	s.WriteEntry(e)
+	s.Close()
+
+	if len(bulk.requests) != 1 || len(bulk.requests[0]) != 1 {
+		t.Fatalf("got requests %v", bulk.requests)
+	}
+	if got := bulk.header.Get("Authorization"); got != "ApiKey key" {
+		t.Errorf("Authorization = %q", got)
+	}
+	item := bulk.requests[0][0]
+	if item.index != "clog-2024.05.01" {
+		t.Errorf("index %q", item.index)
+	}
+	var want map[string]any
+	json.Unmarshal([]byte(`{
+		"@timestamp": "2024-05-01T12:00:00Z",
+		"ecs": {"version": "`+ECSVersion+`"},
+		"message": "failed",
+		"log": {"level": "error", "origin": {"file": {"name": "main.go", "line": 7}}},
+		"error": {"message": "timeout"},
+		"event": {"duration": 1000000000},
+		"clog": {"duration_ms": 1000, "user": "ann"}
+	}`), &want)
+	if !reflect.DeepEqual(item.source, want) {
+		got, _ := json.Marshal(item.source)
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestElasticsearchRetriesRejectedItems(t *testing.T) {
+	bulk := &fakeBulk{seen: map[string]int{}, status: func(message string, attempt int) int {
+		switch {
+		case message == "busy" && attempt == 1:
+			return http.StatusTooManyRequests
+		case message == "invalid":
+			return http.StatusBadRequest
+		}
+		return http.StatusCreated
+	}}
+	srv := httptest.NewServer(bulk)
+	defer srv.Close()
+
+	var errs []error
+	s := NewElasticsearchSink(srv.URL)
+	s.RetryBackoff = time.Millisecond
+	s.OnError = func(err error) { errs = append(errs, err) }
+	for _, msg := range []string{"ok", "busy", "invalid"} {
+		s.WriteEntry(testEntry(LevelError, msg))
+	}
+	s.Close()
+
+	var sent [][]string
+	for _, items := range bulk.requests {
+		var messages []string
+		for _, item := range items {
+			messages = append(messages, item.source["message"].(string))
+		}
+		sent = append(sent, messages)
+	}
+	// only the item rejected with 429 is sent again
+	if want := [][]string{{"ok", "busy", "invalid"}, {"busy"}}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "1 of 3 bulk items failed") ||
+		!strings.Contains(errs[0].Error(), "400 t: invalid") {
+		t.Errorf("got errors %v", errs)
+	}
+}
+
+func TestElasticsearchRetriesFailedRequests(t *testing.T) {
+	var calls int
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		if calls++; calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, `{"errors": false, "items": [{"create": {"status": 201}}]}`)
+	}))
+	defer srv.Close()
+
+	s := NewElasticsearchSink(srv.URL)
+	s.RetryBackoff = time.Millisecond
+	s.WriteEntry(testEntry(LevelError, "hello"))
+	s.Close()
+
+	if len(bodies) != 2 || !bytes.Equal(bodies[0], bodies[1]) {
+		t.Errorf("got %d requests, want the same body twice", len(bodies))
+	}
+}
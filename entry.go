@@ -1,23 +1,38 @@
 package clog
 
 import (
-	"fmt"
+	"context"
 	"os"
+	"time"
 
 	"github.com/elliotchance/orderedmap/v2"
 )
 
 type Entry struct {
-	Logger *Logger
-	Level  Level
-	Error  error
-	Fields *orderedmap.OrderedMap[string, any]
+	Logger     *Logger
+	Level      Level
+	Time       time.Time
+	Message    string
+	Caller     string
+	Error      error
+	Context    context.Context
+	Fields     *orderedmap.OrderedMap[string, any]
+	Kind       string
+	Task       *TaskLogger
+	Event      string
+	Elapsed    time.Duration
+	Repeated   int
+	Backfilled bool
+	// escaped is set once Message went through sprintf, which escapes all
+	// but the Raw arguments; other messages are escaped as a whole.
+	escaped bool
 }
 
 func NewEntry(log *Logger) *Entry {
 	return &Entry{
 		Logger: log,
 		Fields: orderedmap.NewOrderedMap[string, any](),
+		Task:   log.task,
 	}
 }
 
@@ -31,16 +46,24 @@ func (e *Entry) Err(err error) *Entry {
 	return e
 }
 
+// Ctx attaches ctx to the entry, for sinks that read request scoped data
+// such as trace IDs from it.
+func (e *Entry) Ctx(ctx context.Context) *Entry {
+	e.Context = ctx
+	return e
+}
+
 func (e *Entry) Msg(format string, args ...any) {
-	e.Logger.print(e.Level, fmt.Sprintf(format, args...), e)
-	if e.Level == LevelFatal {
-		os.Exit(1)
-	}
+	e.escaped = true
+	e.msg(e.Logger.sprintf(format, args...))
 }
 
 func (e *Entry) msg(msg string) {
-	e.Logger.print(e.Level, msg, e)
+	e.Message = msg
+	e.Logger.print(e)
 	if e.Level == LevelFatal {
+		// finish the run as Close would, the deferred calls will not
+		_ = e.Logger.Close()
 		os.Exit(1)
 	}
 }
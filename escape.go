@@ -0,0 +1,119 @@
+package clog
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type EscapeMode int
+
+const (
+	// EscapeControl renders CR, LF and other control characters as Go-style
+	// escapes (\n, \x1b, \u202e) so they cannot forge lines or restyle the
+	// terminal.
+	EscapeControl EscapeMode = iota
+	// StripControl drops control characters, turning CR and LF into spaces.
+	StripControl
+	// AllowControl writes messages and values verbatim.
+	AllowControl
+)
+
+// Raw marks trusted, possibly pre-styled content that is written without
+// escaping, both as a field value and as a Msg argument.
+type Raw string
+
+func (r Raw) String() string {
+	return string(r)
+}
+
+func isControl(r rune) bool {
+	switch {
+	case r == '\t':
+		return false
+	case r < 0x20, r == 0x7f, r >= 0x80 && r < 0xa0:
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+		// bidirectional overrides can visually reorder the rest of a line
+		return true
+	}
+	return false
+}
+
+func sanitize(mode EscapeMode, s string) string {
+	if mode == AllowControl || strings.IndexFunc(s, isControl) < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for _, r := range s {
+		if !isControl(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if mode == StripControl {
+			if r == '\n' || r == '\r' {
+				b.WriteByte(' ')
+			}
+			continue
+		}
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < utf8.RuneSelf:
+			_, _ = fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			_, _ = fmt.Fprintf(&b, `\u%04x`, r)
+		}
+	}
+	return b.String()
+}
+
+func (l *Logger) sanitize(s string) string {
+	return sanitize(l.Escape, s)
+}
+
+type escaped struct {
+	mode  EscapeMode
+	value any
+}
+
+func (e escaped) Format(f fmt.State, verb rune) {
+	_, _ = f.Write([]byte(sanitize(e.mode, fmt.Sprintf(fmt.FormatString(f, verb), e.value))))
+}
+
+// sprintf formats a message with the escape mode applied to the format and
+// to every argument except Raw ones, so trusted styling survives while
+// user-supplied strings cannot inject control sequences.
+func (l *Logger) sprintf(format string, args ...any) string {
+	if l.Escape == AllowControl {
+		return fmt.Sprintf(format, args...)
+	}
+	safe := make([]any, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case Raw:
+			safe[i] = string(arg)
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, complex64, complex128:
+			safe[i] = arg
+		default:
+			safe[i] = escaped{l.Escape, arg}
+		}
+	}
+	return fmt.Sprintf(l.sanitize(format), safe...)
+}
+
+// valueString renders a field value as a single line of text for the
+// text-based formatters.
+func (l *Logger) valueString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case Raw:
+		return string(value)
+	}
+	return l.sanitize(fmt.Sprint(value))
+}
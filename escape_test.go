@@ -0,0 +1,97 @@
+package clog
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	for _, tt := range []struct {
+		mode EscapeMode
+		in   string
+		want string
+	}{
+		{EscapeControl, "plain\ttext", "plain\ttext"},
+		{EscapeControl, "a\nb\rc", `a\nb\rc`},
+		{EscapeControl, "\x1b[31mred", `\x1b[31mred`},
+		{EscapeControl, "evil\u202etxt", `evil\u202etxt`},
+		{StripControl, "a\nb\x1b[0m", "a b[0m"},
+		{AllowControl, "a\nb\x1b", "a\nb\x1b"},
+	} {
+		if got := sanitize(tt.mode, tt.in); got != tt.want {
+			t.Errorf("sanitize(%d, %q) = %q, want %q", tt.mode, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSprintfEscapesArguments(t *testing.T) {
+	l := New()
+	got := l.sprintf("user %s said %q (%d) %s", "ann\nINFO forged", "hi\x1b", 3, Raw("\x1b[1mbold\x1b[0m"))
+	want := `user ann\nINFO forged said "hi\x1b" (3) ` + "\x1b[1mbold\x1b[0m"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := l.sprintf("line\n%d", 1); got != `line\n1` {
+		t.Errorf("format not escaped: %q", got)
+	}
+}
+
+func TestLoggerEscapesOutput(t *testing.T) {
+	l, b := bufferLogger()
+	l.Info().Any("k\ney", "v\nal").Any("raw", Raw("\x1b[1m")).Msg("hello %s", "x\ny")
+	out := b.String()
+	if strings.Count(out, "\n") != 3 {
+		t.Errorf("injected lines: %q", out)
+	}
+	for _, want := range []string{`hello x\ny`, `k\ney: `, `v\nal`, "\x1b[1m"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+
+	b.Reset()
+	l.SetEscapeMode(AllowControl)
+	l.Info().Msg("a\nb")
+	if !strings.Contains(b.String(), "a\n  b") {
+		t.Errorf("AllowControl escaped: %q", b.String())
+	}
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetFormatter(&LogfmtFormatter{})
+	l.Info().Any("user", "ann lee").Any("a=b", "x\ny").Any("n", 1).Msg("hi")
+	out := b.String()
+	out = out[strings.Index(out, " level="):]
+	want := ` level=info msg=hi user="ann lee" a_b="x\\ny" n=1` + "\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetFormatter(&JSONFormatter{})
+	l.Error().Err(errors.New("bad\x1b")).Any("n", 2).Any("s", "a\nb").Msg("<%s>", "x")
+
+	var got map[string]any
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %q", err, b.String())
+	}
+	for key, want := range map[string]any{
+		"level": "error",
+		"msg":   "<x>",
+		"n":     2.0,
+		"s":     `a\nb`,
+		"err":   `bad\x1b`,
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %#v, want %#v", key, got[key], want)
+		}
+	}
+	if !strings.Contains(b.String(), `"msg":"<x>"`) {
+		t.Errorf("HTML escaped: %s", b.String())
+	}
+}
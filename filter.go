@@ -0,0 +1,479 @@
+package clog
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a parsed filter expression, such as
+//
+//	level>=warn and component=db and msg~"timeout"
+//
+// A comparison is a field, an operator and a value. The operators are = and
+// != for equality, <, <=, > and >= for ordering, and ~ and !~ for matching a
+// regular expression. Values that are numbers, durations or times are
+// compared as such, other values as text. A field on its own matches when
+// the entry has it. Comparisons combine with and, or, not and parentheses;
+// and binds tighter than or, and may be left out.
+//
+// The fields are the entry's fields, with dotted keys for nested objects,
+// and these:
+//
+//	level   the level, compared by severity
+//	msg     the message
+//	time    the time, compared with a time or a duration relative to now
+//	elapsed how long the entry took, for task and timer entries
+//	caller  the caller
+//
+// A missing field matches != and !~ only.
+type Filter struct {
+	text string
+	root filterNode
+}
+
+type filterNode interface {
+	match(e *filterEntry) bool
+}
+
+type (
+	filterAnd    struct{ left, right filterNode }
+	filterOr     struct{ left, right filterNode }
+	filterNot    struct{ node filterNode }
+	filterExists struct{ field string }
+)
+
+type filterCompare struct {
+	field string
+	op    string
+	value filterValue
+	re    *regexp.Regexp
+	level Level
+}
+
+// filterValue is a value in an expression, with the types it parses as.
+type filterValue struct {
+	text     string
+	number   float64
+	isNumber bool
+	duration time.Duration
+	isDur    bool
+	time     time.Time
+	isTime   bool
+}
+
+func (n filterAnd) match(e *filterEntry) bool    { return n.left.match(e) && n.right.match(e) }
+func (n filterOr) match(e *filterEntry) bool     { return n.left.match(e) || n.right.match(e) }
+func (n filterNot) match(e *filterEntry) bool    { return !n.node.match(e) }
+func (n filterExists) match(e *filterEntry) bool { _, ok := e.get(n.field); return ok }
+
+// ParseFilter parses a filter expression. The empty expression matches
+// every entry.
+func ParseFilter(text string) (*Filter, error) {
+	p := &filterParser{text: text}
+	p.next()
+	if p.tok.kind == tokEOF {
+		return &Filter{text: text}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return &Filter{text: text, root: root}, nil
+}
+
+// MustParseFilter is like ParseFilter but panics on errors.
+func MustParseFilter(text string) *Filter {
+	f, err := ParseFilter(text)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func (f *Filter) String() string {
+	return f.text
+}
+
+// Match reports whether e matches the expression.
+func (f *Filter) Match(e *Entry) bool {
+	if f == nil || f.root == nil {
+		return true
+	}
+	return f.root.match(&filterEntry{Entry: e})
+}
+
+// SetFilter drops the entries that do not match filter, for the writer and
+// the sinks alike. FilterSink filters a single sink.
+func SetFilter(filter *Filter) *Logger {
+	return logger.SetFilter(filter)
+}
+
+func (l *Logger) SetFilter(filter *Filter) *Logger {
+	if l.config != nil {
+		l.config.update(func(s *levelSettings) { s.filter = filter })
+		return l
+	}
+	l.Filter = filter
+	return l
+}
+
+// FilterSink passes the entries that match Filter on to Sink.
+type FilterSink struct {
+	Sink
+	Filter *Filter
+}
+
+func (s *FilterSink) WriteEntry(e *Entry) error {
+	if !s.Filter.Match(e) {
+		return nil
+	}
+	return s.Sink.WriteEntry(e)
+}
+
+// filterEntry looks up the fields of an entry, flattening them on first use.
+type filterEntry struct {
+	*Entry
+	fields map[string]string
+}
+
+func (e *filterEntry) get(field string) (string, bool) {
+	switch field {
+	case "level":
+		return e.Level.String(), true
+	case "msg", "message":
+		return e.Message, true
+	case "time":
+		return e.Time.Format(time.RFC3339Nano), true
+	case "elapsed":
+		return e.Elapsed.String(), e.Elapsed > 0
+	}
+	if e.fields == nil {
+		e.fields = map[string]string{}
+		for _, arg := range sinkFields(e.Entry) {
+			if _, ok := e.fields[arg.Key]; !ok {
+				e.fields[arg.Key] = arg.Value.(string)
+			}
+		}
+	}
+	value, ok := e.fields[field]
+	return value, ok
+}
+
+func (n *filterCompare) match(e *filterEntry) bool {
+	negate := n.op == "!=" || n.op == "!~"
+	switch n.field {
+	case "level":
+		if n.re == nil {
+			return compare(n.op, int(e.Level), int(n.level))
+		}
+	case "time":
+		if n.re != nil {
+			break
+		}
+		if n.value.isDur {
+			return compare(n.op, e.Time.Compare(time.Now().Add(n.value.duration)), 0)
+		}
+		return compare(n.op, e.Time.Compare(n.value.time), 0)
+	case "elapsed":
+		if n.value.isDur && e.Elapsed > 0 {
+			return compare(n.op, e.Elapsed, n.value.duration)
+		}
+	}
+
+	text, ok := e.get(n.field)
+	if !ok {
+		return negate
+	}
+	if n.re != nil {
+		return n.re.MatchString(text) != negate
+	}
+	value := parseFilterValue(text)
+	switch {
+	case n.value.isNumber && value.isNumber:
+		return compare(n.op, value.number, n.value.number)
+	case n.value.isDur && value.isDur:
+		return compare(n.op, value.duration, n.value.duration)
+	case n.value.isTime && value.isTime:
+		return compare(n.op, value.time.Compare(n.value.time), 0)
+	case n.op == "=" || n.op == "!=":
+		return compare(n.op, text, n.value.text)
+	}
+	// text has no order
+	return false
+}
+
+func compare[T int | float64 | time.Duration | string](op string, a, b T) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+var filterTimeLayouts = append(timeLayouts, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02")
+
+func parseFilterValue(text string) filterValue {
+	v := filterValue{text: text}
+	if n, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+		v.number, v.isNumber = n, true
+		return v
+	}
+	if d, err := time.ParseDuration(text); err == nil {
+		v.duration, v.isDur = d, true
+		return v
+	}
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			v.time, v.isTime = t, true
+			break
+		}
+	}
+	return v
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type filterParser struct {
+	text string
+	pos  int
+	tok  filterToken
+	err  error
+}
+
+func (p *filterParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("clog: filter %q: at %d: %s", p.text, p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+func isFilterDelim(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`()"=!<>~&|`, r)
+}
+
+// next reads the next token into p.tok.
+func (p *filterParser) next() {
+	for p.pos < len(p.text) && unicode.IsSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	rest := p.text[p.pos:]
+	tok := func(kind tokenKind, n int) {
+		p.tok = filterToken{kind: kind, text: rest[:n], pos: start}
+		p.pos += n
+	}
+	switch {
+	case rest == "":
+		tok(tokEOF, 0)
+	case rest[0] == '(':
+		tok(tokLParen, 1)
+	case rest[0] == ')':
+		tok(tokRParen, 1)
+	case strings.HasPrefix(rest, "&&"):
+		tok(tokAnd, 2)
+	case strings.HasPrefix(rest, "||"):
+		tok(tokOr, 2)
+	case strings.HasPrefix(rest, "!="), strings.HasPrefix(rest, "!~"), strings.HasPrefix(rest, "<="),
+		strings.HasPrefix(rest, ">="), strings.HasPrefix(rest, "=="):
+		tok(tokOp, 2)
+		if p.tok.text == "==" {
+			p.tok.text = "="
+		}
+	case rest[0] == '!':
+		tok(tokNot, 1)
+	case strings.ContainsRune("=<>~", rune(rest[0])):
+		tok(tokOp, 1)
+	case rest[0] == '"':
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			p.tok = filterToken{kind: tokString, pos: start}
+			p.err = fmt.Errorf("unterminated string")
+			p.pos = len(p.text)
+			return
+		}
+		tok(tokString, len(quoted))
+		p.tok.text, _ = strconv.Unquote(quoted)
+	default:
+		n := strings.IndexFunc(rest, isFilterDelim)
+		if n < 0 {
+			n = len(rest)
+		}
+		if n == 0 {
+			tok(tokWord, 1)
+			p.err = fmt.Errorf("unexpected %q", p.tok.text)
+			return
+		}
+		tok(tokWord, n)
+		switch strings.ToLower(p.tok.text) {
+		case "and":
+			p.tok.kind = tokAnd
+		case "or":
+			p.tok.kind = tokOr
+		case "not":
+			p.tok.kind = tokNot
+		}
+	}
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.tok.kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokString, tokNot, tokLParen:
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.tok.kind == tokNot {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	if p.err != nil {
+		return nil, p.errorf("%v", p.err)
+	}
+	switch p.tok.kind {
+	case tokLParen:
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ) instead of %s", p.tok)
+		}
+		p.next()
+		return node, nil
+	case tokWord, tokString:
+	default:
+		return nil, p.errorf("expected a field instead of %s", p.tok)
+	}
+
+	field := p.tok.text
+	p.next()
+	if p.tok.kind != tokOp {
+		return filterExists{field}, nil
+	}
+	op := p.tok.text
+	p.next()
+	if p.err != nil {
+		return nil, p.errorf("%v", p.err)
+	}
+	if p.tok.kind != tokWord && p.tok.kind != tokString {
+		return nil, p.errorf("expected a value after %s%s instead of %s", field, op, p.tok)
+	}
+	n := &filterCompare{field: field, op: op, value: parseFilterValue(p.tok.text)}
+	if err := p.check(n); err != nil {
+		return nil, err
+	}
+	p.next()
+	return n, nil
+}
+
+// check validates the value of a comparison for its field and operator.
+func (p *filterParser) check(n *filterCompare) error {
+	if n.op == "~" || n.op == "!~" {
+		re, err := regexp.Compile(n.value.text)
+		if err != nil {
+			return p.errorf("bad regular expression: %v", err)
+		}
+		n.re = re
+		return nil
+	}
+	switch n.field {
+	case "level":
+		level, err := ParseLevel(n.value.text)
+		if err != nil {
+			return p.errorf("unknown level %s", p.tok)
+		}
+		n.level = level
+	case "time":
+		if !n.value.isTime && !n.value.isDur {
+			return p.errorf("expected a time or duration instead of %s", p.tok)
+		}
+	case "elapsed":
+		if !n.value.isDur {
+			return p.errorf("expected a duration instead of %s", p.tok)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package clog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"level>=warn",
+		"level>=warn and component=db",
+		"level>=warn component=db",
+		`msg~"time(out)?" || user`,
+		"not (a=1 or b!=2) && !c",
+		"elapsed>1s",
+		"time>-1h",
+		"time<2024-05-01",
+		`"odd key"="a b"`,
+		"a==1",
+	} {
+		f, err := ParseFilter(text)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", text, err)
+			continue
+		}
+		if f.String() != text {
+			t.Errorf("String() = %q, want %q", f.String(), text)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		err  string
+	}{
+		{"level>=", `at 8: expected a value after level>= instead of end of expression`},
+		{"level>=loud", `at 8: unknown level "loud"`},
+		{"a=1 and", `at 8: expected a field instead of end of expression`},
+		{"(a=1", `at 5: expected ) instead of end of expression`},
+		{"a=1)", `at 4: unexpected ")"`},
+		{`msg~"("`, `at 5: bad regular expression`},
+		{`msg="open`, `at 5: unterminated string`},
+		{"elapsed>5", `at 9: expected a duration instead of "5"`},
+		{"time>soon", `at 6: expected a time or duration instead of "soon"`},
+		{"or a", `at 1: expected a field instead of "or"`},
+		{"a=1 & b", `at 5: unexpected "&"`},
+	} {
+		_, err := ParseFilter(tt.text)
+		if err == nil {
+			t.Errorf("ParseFilter(%q) succeeded", tt.text)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseFilter(%q) = %v, want %q", tt.text, err, tt.err)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	e := testEntry(LevelWarn, "connection timeout")
+	e.Time = time.Now().Add(-time.Minute)
+	e.Elapsed = 2 * time.Second
+	e.Any("component", "db").Any("retries", 3).Any("latency", "150ms").
+		Any("user", Object{{Key: "name", Value: "ann"}}).Any("odd key", "a b")
+
+	for _, tt := range []struct {
+		text string
+		want bool
+	}{
+		{"", true},
+		{"level>=warn", true},
+		{"level>warn", false},
+		{"level=warning", true},
+		{"level~^wa", true},
+		{"component=db", true},
+		{"component!=db", false},
+		{"component=DB", false},
+		{"retries>2", true},
+		{"retries>=10", false},
+		{"retries=3.0", true},
+		{"latency<1s", true},
+		{"latency>100", false},
+		{`msg~"time(out)?"`, true},
+		{"message!~timeout", false},
+		{"user.name=ann", true},
+		{"user", false},
+		{"user.name", true},
+		{"missing", false},
+		{"missing=x", false},
+		{"missing!=x", true},
+		{"missing!~x", true},
+		{"missing<5", false},
+		{"component>db", false},
+		{"elapsed>1s", true},
+		{"elapsed>=5s", false},
+		{"time>-1h", true},
+		{"time>-10s", false},
+		{"time<2000-01-01", false},
+		{`"odd key"="a b"`, true},
+		{"level>=error or component=db", true},
+		{"level>=error component=db", false},
+		{"not component=db", false},
+		{"!(component=web) and retries=3", true},
+		{"component=web or component=db and retries=4", false},
+		{"(component=web or component=db) and retries=3", true},
+	} {
+		if got := MustParseFilter(tt.text).Match(e); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFilterBeforeRedaction(t *testing.T) {
+	var b bytes.Buffer
+	l := &Logger{Writer: &b, Formatter: &LogfmtFormatter{}}
+	l.SetRedactor(NewRedactor())
+	l.SetFilter(MustParseFilter("token=abc"))
+	l.Info().Any("token", "abc").Msg("kept")
+	l.Info().Any("token", "xyz").Msg("dropped")
+
+	out := b.String()
+	if !strings.Contains(out, "kept") || strings.Contains(out, "dropped") {
+		t.Errorf("got %q", out)
+	}
+	if strings.Contains(out, "abc") {
+		t.Errorf("the token was not redacted: %q", out)
+	}
+}
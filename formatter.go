@@ -0,0 +1,174 @@
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type Formatter interface {
+	Format(e *Entry) []byte
+}
+
+type PrettyFormatter struct {
+	MaxDepth int
+	MaxItems int
+	MaxWidth int
+	// AlignFields pads sibling keys so their values line up in a column.
+	AlignFields bool
+	// Truncate cuts values that do not fit the terminal width with an
+	// ellipsis instead of wrapping them onto continuation lines.
+	Truncate bool
+}
+
+func (f *PrettyFormatter) Format(e *Entry) []byte {
+	entry := f.format(e)
+	if e.Logger.NoColor {
+		entry = stripANSI(entry)
+	}
+	return entry
+}
+
+func (f *PrettyFormatter) format(e *Entry) []byte {
+	pal := e.Logger.palette()
+	style := pal.levels[e.Level]
+
+	nodes := make([]treeNode, 0, e.Fields.Len()+1)
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		nodes = append(nodes, treeNode{key: it.Key, value: it.Value})
+	}
+	if e.Caller != "" {
+		nodes = append(nodes, treeNode{key: "caller", value: Raw(lipgloss.NewStyle().Foreground(pal.muted).Render(e.Caller))})
+	}
+	tree := f.newTreeWriter(e, pal, style.Key.Copy().Foreground(style.Color))
+
+	if isBlock(e.Kind) {
+		return f.renderBlock(e, tree, nodes)
+	}
+
+	header := f.renderTimestamp(e, pal) + style.Icon.Copy().Foreground(style.Color).Render("") + f.renderLevelText(e, pal)
+	if e.Logger.Compact {
+		if line, ok := f.compactLine(e, tree, header, nodes); ok {
+			return f.indent(e, []byte(line))
+		}
+	}
+
+	var b bytes.Buffer
+	tree.b = &b
+	b.WriteString(header)
+
+	indent, width := lipgloss.Width(header), f.width(e)
+	if width > 0 {
+		width = max(width-indent, minWrapWidth)
+	}
+	for i, line := range fitLines(e.Message, width, f.Truncate) {
+		if i > 0 {
+			b.WriteString("\n" + strings.Repeat(" ", indent))
+		}
+		b.WriteString(style.Message.Copy().Foreground(style.Color).Render(line))
+	}
+	b.WriteString(f.renderSuffix(e, pal))
+
+	tree.writeNodes("", nodes, 0)
+
+	return f.indent(e, b.Bytes())
+}
+
+// indent shifts every line of a task's entries one level per enclosing task
+// and terminates the entry.
+func (f *PrettyFormatter) indent(e *Entry, entry []byte) []byte {
+	if e.Logger.indent > 0 {
+		pad := bytes.Repeat([]byte("  "), e.Logger.indent)
+		entry = append(pad, bytes.ReplaceAll(entry, []byte("\n"), append([]byte("\n"), pad...))...)
+	}
+	return append(entry, '\n')
+}
+
+// width is the output width left after task indentation, or 0 when output
+// is not wrapped.
+func (f *PrettyFormatter) width(e *Entry) int {
+	width := e.Logger.width()
+	if width <= 0 {
+		return 0
+	}
+	return max(width-2*e.Logger.indent, minWrapWidth)
+}
+
+// compactLine renders the entry on a single line with its fields inline. It
+// fails when a value expands into a subtree, spans several lines or the
+// line would be wider than the output.
+func (f *PrettyFormatter) compactLine(e *Entry, tree *treeWriter, header string, nodes []treeNode) (string, bool) {
+	style := tree.palette.levels[e.Level]
+	if strings.Contains(e.Message, "\n") {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteString(style.Message.Copy().Foreground(style.Color).Render(e.Message))
+	b.WriteString(f.renderSuffix(e, tree.palette))
+	for _, node := range nodes {
+		if _, expanded := tree.children(node.value, 0); expanded {
+			return "", false
+		}
+		value, render := tree.scalar(node.value, true)
+		if strings.Contains(value, "\n") {
+			return "", false
+		}
+		key := e.Logger.sanitize(node.key)
+		if key != "" && value != "" {
+			key += "="
+		}
+		b.WriteString("  ")
+		b.WriteString(tree.keyStyle.Render(key))
+		b.WriteString(render(value))
+	}
+
+	line := b.String()
+	if width := f.width(e); width > 0 && lipgloss.Width(line) > width {
+		return "", false
+	}
+	return line, true
+}
+
+func (f *PrettyFormatter) renderTimestamp(e *Entry, pal *palette) string {
+	if !e.Logger.ShowTime {
+		return ""
+	}
+	return fmt.Sprintf("%s %s ",
+		lipgloss.NewStyle().Foreground(pal.muted).Render(e.Time.Format(e.Logger.TimeFormat)),
+		pal.divide.Render(),
+	)
+}
+
+func (f *PrettyFormatter) renderLevelText(e *Entry, pal *palette) string {
+	if !e.Logger.ShowLevelText {
+		return ""
+	}
+	text, style := strings.ToUpper(e.Level.String()), pal.levels[e.Level]
+	return fmt.Sprintf("%s%s ",
+		style.Text.Copy().Foreground(style.Color).Width(8).SetString(text).Render(),
+		pal.divide.Render(),
+	)
+}
+
+// renderSuffix adds how long the entry took, how often it repeated and
+// whether it was backfilled after the message.
+func (f *PrettyFormatter) renderSuffix(e *Entry, pal *palette) string {
+	var parts []string
+	if e.Elapsed > 0 {
+		parts = append(parts, formatElapsed(e.Elapsed))
+	}
+	if e.Repeated > 0 && e.Kind != KindRepeat {
+		parts = append(parts, fmt.Sprintf("(x%d)", e.Repeated))
+	}
+	if e.Backfilled {
+		parts = append(parts, "(backfilled)")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + lipgloss.NewStyle().Foreground(pal.muted).Render(strings.Join(parts, " "))
+}
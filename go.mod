@@ -5,15 +5,15 @@ go 1.22.0
 require (
 	github.com/charmbracelet/lipgloss v0.10.0
 	github.com/elliotchance/orderedmap/v2 v2.2.0
+	github.com/mattn/go-isatty v0.0.18
+	github.com/mattn/go-runewidth v0.0.15
+	github.com/muesli/reflow v0.3.0
 	github.com/muesli/termenv v0.15.2
+	golang.org/x/sys v0.16.0
 )
 
 require (
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-isatty v0.0.18 // indirect
-	github.com/mattn/go-runewidth v0.0.15 // indirect
-	github.com/muesli/reflow v0.3.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	golang.org/x/sys v0.16.0 // indirect
 )
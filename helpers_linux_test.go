@@ -0,0 +1,73 @@
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// testTerminal is the writing end of a pseudo-terminal, with everything
+// written to it collected from the other end.
+type testTerminal struct {
+	*os.File
+	out  bytes.Buffer
+	done chan struct{}
+}
+
+func newTestTerminal(t *testing.T, width int) *testTerminal {
+	t.Helper()
+	ptmx, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	fd := int(ptmx.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		t.Fatal(err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pts, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+
+	// written bytes should arrive as they are, without \n turning into \r\n
+	termios, err := unix.IoctlGetTermios(int(pts.Fd()), unix.TCGETS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	termios.Oflag &^= unix.OPOST
+	if err := unix.IoctlSetTermios(int(pts.Fd()), unix.TCSETS, termios); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.IoctlSetWinsize(int(pts.Fd()), unix.TIOCSWINSZ, &unix.Winsize{Col: uint16(width), Row: 24}); err != nil {
+		t.Fatal(err)
+	}
+	// descriptors are reused, so forget widths cached for earlier ones
+	resetTerminalWidths()
+
+	term := &testTerminal{File: pts, done: make(chan struct{})}
+	go func() {
+		defer close(term.done)
+		_, _ = io.Copy(&term.out, ptmx)
+	}()
+	t.Cleanup(func() {
+		pts.Close()
+		ptmx.Close()
+	})
+	return term
+}
+
+// String closes the terminal and returns what was written to it.
+func (term *testTerminal) String() string {
+	term.File.Close()
+	<-term.done
+	return term.out.String()
+}
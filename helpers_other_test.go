@@ -0,0 +1,21 @@
+//go:build !linux
+
+package clog
+
+import (
+	"os"
+	"testing"
+)
+
+type testTerminal struct {
+	*os.File
+}
+
+func newTestTerminal(t *testing.T, width int) *testTerminal {
+	t.Skip("pseudo-terminals are only set up on linux")
+	return nil
+}
+
+func (term *testTerminal) String() string {
+	return ""
+}
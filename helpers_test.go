@@ -0,0 +1,31 @@
+package clog
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// uncolored removes the escape sequences styling adds to the output.
+func uncolored(s string) string {
+	return string(stripANSI([]byte(s)))
+}
+
+// bufferLogger returns a logger writing to the returned buffer.
+func bufferLogger() (*Logger, *bytes.Buffer) {
+	var b bytes.Buffer
+	l := New()
+	l.Writer = &b
+	return l, &b
+}
+
+// testTime is the time of the entries made by testEntry.
+var testTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// testEntry returns an entry as a logger passes it to its sinks, for the
+// sink tests.
+func testEntry(level Level, msg string) *Entry {
+	e := NewEntry(&Logger{Writer: io.Discard})
+	e.Level, e.Time, e.Message = level, testTime, msg
+	return e
+}
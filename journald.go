@@ -0,0 +1,154 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const JournalSocket = "/run/systemd/journal/socket"
+
+// JournalSink sends entries to systemd-journald with the native protocol,
+// so every field shows up as a journal field. Entries too large for a
+// datagram are passed in a sealed memfd where the platform supports it.
+type JournalSink struct {
+	Identifier string
+	// WriteTimeout bounds each write, so a stalled journald drops entries
+	// instead of holding up logging.
+	WriteTimeout time.Duration
+
+	addr *net.UnixAddr
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// DialJournal connects to the journal socket at path, or to JournalSocket
+// when path is empty.
+func DialJournal(path string) (*JournalSink, error) {
+	if path == "" {
+		path = JournalSocket
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	// an unconnected socket, as passing a memfd takes a destination address
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournalSink{
+		Identifier:   filepath.Base(os.Args[0]),
+		WriteTimeout: time.Second,
+		addr:         &net.UnixAddr{Name: path, Net: "unixgram"},
+		conn:         conn,
+	}, nil
+}
+
+// WriteEntry sends e in a datagram. Since the socket is not connected, a
+// restarted journald is picked up without reconnecting.
+func (s *JournalSink) WriteEntry(e *Entry) error {
+	data := s.encode(e)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return net.ErrClosed
+	}
+	if s.WriteTimeout > 0 {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+	}
+	_, _, err := s.conn.WriteMsgUnix(data, nil, s.addr)
+	if tooLarge(err) {
+		return sendMemfd(s.conn, s.addr, data)
+	}
+	return err
+}
+
+func (s *JournalSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *JournalSink) encode(e *Entry) []byte {
+	var b bytes.Buffer
+	priority := 6
+	if e.Level >= 0 && int(e.Level) < len(syslogSeverity) {
+		priority = syslogSeverity[e.Level]
+	}
+	writeJournalField(&b, "PRIORITY", string(rune('0'+priority)))
+	writeJournalField(&b, "MESSAGE", e.Message)
+	if s.Identifier != "" {
+		writeJournalField(&b, "SYSLOG_IDENTIFIER", s.Identifier)
+	}
+	if i := strings.LastIndexByte(e.Caller, ':'); i >= 0 {
+		writeJournalField(&b, "CODE_FILE", e.Caller[:i])
+		writeJournalField(&b, "CODE_LINE", e.Caller[i+1:])
+	}
+	for _, field := range sinkFields(e) {
+		if field.Key == "caller" && e.Caller != "" {
+			continue
+		}
+		writeJournalField(&b, journalKey(field.Key), field.Value.(string))
+	}
+	return b.Bytes()
+}
+
+// writeJournalField writes KEY=value, or for values containing a newline
+// the key, the value's length as a little endian uint64 and the value.
+func writeJournalField(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalKey turns a field name into a journal field name: upper case
+// letters, digits and underscores, at most 64 characters. Names journald
+// gives a meaning to, those starting with an underscore, which are set by
+// journald itself, and those starting with a digit get an F_ prefix, so a
+// field named "message" cannot pass for the entry's MESSAGE.
+func journalKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	if key == "" || journalReserved(key) {
+		key = "F_" + key
+	}
+	if len(key) > 64 {
+		key = key[:64]
+	}
+	return key
+}
+
+func journalReserved(key string) bool {
+	switch key {
+	case "PRIORITY", "MESSAGE", "MESSAGE_ID", "ERRNO", "TID":
+		return true
+	}
+	return key[0] == '_' || key[0] >= '0' && key[0] <= '9' ||
+		strings.HasPrefix(key, "CODE_") || strings.HasPrefix(key, "SYSLOG_")
+}
@@ -0,0 +1,34 @@
+package clog
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func tooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE)
+}
+
+// sendMemfd writes data to a sealed memfd and passes its descriptor to
+// journald, which reads the entry from it.
+func sendMemfd(conn *net.UnixConn, addr *net.UnixAddr, data []byte) error {
+	fd, err := unix.MemfdCreate("clog-journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "clog-journal")
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL); err != nil {
+		return err
+	}
+	_, _, err = conn.WriteMsgUnix(nil, unix.UnixRights(int(file.Fd())), addr)
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package clog
+
+import (
+	"errors"
+	"net"
+)
+
+func tooLarge(err error) bool {
+	return false
+}
+
+func sendMemfd(conn *net.UnixConn, addr *net.UnixAddr, data []byte) error {
+	return errors.New("clog: journal entry too large")
+}
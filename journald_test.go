@@ -0,0 +1,89 @@
+package clog
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"user.id":           "USER_ID",
+		"message":           "F_MESSAGE",
+		"priority":          "F_PRIORITY",
+		"syslog_identifier": "F_SYSLOG_IDENTIFIER",
+		"code_file":         "F_CODE_FILE",
+		"_pid":              "F__PID",
+		"2fa":               "F_2FA",
+		"":                  "F_",
+	} {
+		if got := journalKey(key); got != want {
+			t.Errorf("journalKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestJournalSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	s, err := DialJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Identifier = "app"
+
+	e := testEntry(LevelError, "two\nlines")
+	e.Any("message", "spoofed").Any("priority", 7).Any("_pid", 1)
+	if err := s.WriteEntry(e); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "PRIORITY=3\nMESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\n" +
+		"SYSLOG_IDENTIFIER=app\nF_MESSAGE=spoofed\nF_PRIORITY=7\nF__PID=1\n"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestJournalWriteTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	s, err := DialJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.WriteTimeout = 10 * time.Millisecond
+
+	// nothing reads from conn, so its queue fills up and writes block
+	for range 10000 {
+		err := s.WriteEntry(testEntry(LevelInfo, "stalled"))
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Skip("writes to a full socket did not block")
+}
@@ -0,0 +1,91 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type JSONFormatter struct {
+	TimeFormat string
+}
+
+func (f *JSONFormatter) Format(e *Entry) []byte {
+	l := e.Logger
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	writeJSONField(&b, "time", e.Time.Format(timeFormat))
+	b.WriteByte(',')
+	writeJSONField(&b, "level", e.Level.String())
+	b.WriteByte(',')
+	writeJSONField(&b, "msg", e.Message)
+	if e.Caller != "" {
+		b.WriteByte(',')
+		writeJSONField(&b, "caller", e.Caller)
+	}
+	for _, arg := range eventFields(e) {
+		b.WriteByte(',')
+		writeJSONField(&b, arg.Key, arg.Value)
+	}
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		b.WriteByte(',')
+		writeJSONField(&b, l.sanitize(it.Key), l.jsonValue(it.Value))
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// jsonValue applies the escape mode to string-like values so the decoded
+// JSON carries the same text the pretty and logfmt formatters print.
+func (l *Logger) jsonValue(value any) any {
+	switch value := value.(type) {
+	case Raw:
+		return string(value)
+	case string:
+		return l.sanitize(value)
+	case Object:
+		obj := make(Object, len(value))
+		for i, arg := range value {
+			obj[i] = Argument{Key: l.sanitize(arg.Key), Value: l.jsonValue(arg.Value)}
+		}
+		return obj
+	case error:
+		return l.sanitize(value.Error())
+	case json.Marshaler:
+		return value
+	case fmt.Stringer:
+		return l.sanitize(value.String())
+	}
+	return value
+}
+
+func writeJSONField(b *bytes.Buffer, key string, value any) {
+	writeJSON(b, key)
+	b.WriteByte(':')
+	writeJSON(b, value)
+}
+
+func writeJSON(b *bytes.Buffer, value any) {
+	data, err := marshalJSON(value)
+	if err != nil {
+		data, _ = marshalJSON(fmt.Sprint(value))
+	}
+	b.Write(data)
+}
+
+func marshalJSON(value any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	// Encode terminates every value with a newline
+	return bytes.TrimSuffix(b.Bytes(), []byte{'\n'}), nil
+}
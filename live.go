@@ -0,0 +1,160 @@
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/muesli/reflow/truncate"
+)
+
+// liveItem is something redrawn in place below the scrolling log output.
+type liveItem interface {
+	render(width int) string
+}
+
+// liveRegion keeps live items pinned below regular entries. It is shared by
+// a logger and its clones and guarded by their common lock: every write
+// erases the region, emits the entry and draws the region again, so log
+// lines scroll above the live items without corrupting them.
+type liveRegion struct {
+	writer io.Writer
+	items  []liveItem
+	lines  int
+	stop   chan struct{}
+}
+
+// liveInterval caps how often live items are redrawn.
+const liveInterval = 80 * time.Millisecond
+
+// live reports whether live items can be drawn in place, which takes a
+// terminal and the pretty formatter.
+func (l *Logger) live() bool {
+	_, pretty := l.formatter().(*PrettyFormatter)
+	return pretty && isTerminal(l.Writer)
+}
+
+// sameWriter reports whether a and b are the same destination. Comparing
+// the interfaces directly panics for writers of an uncomparable type, such
+// as a struct holding a slice; those count as different.
+func sameWriter(a, b io.Writer) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return va.IsValid() == vb.IsValid()
+	}
+	return va.Type() == vb.Type() && va.Comparable() && va.Equal(vb)
+}
+
+func (l *Logger) write(p []byte) {
+	l.rewrite(0, p)
+}
+
+// rewrite replaces the last lines written above the live region with p.
+func (l *Logger) rewrite(lines int, p []byte) {
+	r := l.regionState()
+	live := len(r.items) > 0 && sameWriter(r.writer, l.Writer)
+	if !live && lines == 0 {
+		_, _ = l.Writer.Write(p)
+		return
+	}
+	var b bytes.Buffer
+	if live {
+		r.erase(&b)
+	}
+	if lines > 0 {
+		_, _ = fmt.Fprintf(&b, "\x1b[%dA\r\x1b[J", lines)
+	}
+	b.Write(p)
+	if live {
+		r.draw(&b)
+	}
+	_, _ = l.Writer.Write(b.Bytes())
+}
+
+func (l *Logger) addLive(item liveItem) {
+	l.lock()
+	defer l.unlock()
+
+	r := l.regionState()
+	if len(r.items) == 0 {
+		r.writer = l.Writer
+		r.stop = make(chan struct{})
+		go l.animate(r.stop)
+	}
+	r.items = append(r.items, item)
+	r.redraw()
+}
+
+// removeLive drops item from the region and writes p, usually the item's
+// final status line, in its place.
+func (l *Logger) removeLive(item liveItem, p []byte) {
+	l.lock()
+	defer l.unlock()
+
+	r := l.regionState()
+	var b bytes.Buffer
+	r.erase(&b)
+	for i, it := range r.items {
+		if it == item {
+			r.items = append(r.items[:i], r.items[i+1:]...)
+			break
+		}
+	}
+	b.Write(p)
+	r.draw(&b)
+	if r.writer != nil {
+		_, _ = r.writer.Write(b.Bytes())
+	}
+	if len(r.items) == 0 && r.stop != nil {
+		close(r.stop)
+		r.stop, r.writer = nil, nil
+	}
+}
+
+func (l *Logger) animate(stop chan struct{}) {
+	ticker := time.NewTicker(liveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.lock()
+			l.regionState().redraw()
+			l.unlock()
+		}
+	}
+}
+
+func (r *liveRegion) redraw() {
+	if r.writer == nil {
+		return
+	}
+	var b bytes.Buffer
+	r.erase(&b)
+	r.draw(&b)
+	_, _ = r.writer.Write(b.Bytes())
+}
+
+func (r *liveRegion) erase(b *bytes.Buffer) {
+	if r.lines > 0 {
+		_, _ = fmt.Fprintf(b, "\x1b[%dA\r\x1b[J", r.lines)
+		r.lines = 0
+	}
+}
+
+func (r *liveRegion) draw(b *bytes.Buffer) {
+	width := terminalWidth(r.writer)
+	for _, item := range r.items {
+		line := item.render(width)
+		if width > 0 {
+			// a wrapped line would throw off the count of lines to erase
+			line = truncate.String(line, uint(width-1))
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+		r.lines++
+	}
+}
@@ -0,0 +1,109 @@
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LogfmtFormatter struct {
+	TimeFormat string
+}
+
+func (f *LogfmtFormatter) Format(e *Entry) []byte {
+	l := e.Logger
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+
+	var b bytes.Buffer
+	writeLogfmtField(&b, "time", e.Time.Format(timeFormat))
+	writeLogfmtField(&b, "level", e.Level.String())
+	writeLogfmtField(&b, "msg", e.Message)
+	if e.Caller != "" {
+		writeLogfmtField(&b, "caller", e.Caller)
+	}
+	for _, arg := range eventFields(e) {
+		writeLogfmtField(&b, arg.Key, fmt.Sprint(arg.Value))
+	}
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		l.writeLogfmtValue(&b, l.sanitize(it.Key), it.Value)
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+func (l *Logger) writeLogfmtValue(b *bytes.Buffer, key string, value any) {
+	l.flatten(key, value, func(key, value string) {
+		writeLogfmtField(b, logfmtKey(key), value)
+	})
+}
+
+// flatten calls add for every leaf of value, naming nested objects with
+// dotted keys. Other composite values are encoded as JSON.
+func (l *Logger) flatten(key string, value any, add func(key, value string)) {
+	obj, ok := value.(Object)
+	if !ok {
+		if isComposite(value) {
+			if data, err := marshalJSON(l.jsonValue(value)); err == nil {
+				add(key, string(data))
+				return
+			}
+		}
+		add(key, l.valueString(value))
+		return
+	}
+	for _, arg := range obj {
+		l.flatten(key+"."+l.sanitize(arg.Key), arg.Value, add)
+	}
+}
+
+func writeLogfmtField(b *bytes.Buffer, key, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.IndexFunc(value, isControl) >= 0 {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(value)
+}
+
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// isComposite reports whether value is a table, map, list or struct that
+// logfmt carries as a JSON encoded value.
+func isComposite(value any) bool {
+	switch value.(type) {
+	case Table:
+		return true
+	case fmt.Stringer, error:
+		return false
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Struct:
+		return true
+	case reflect.Slice, reflect.Array:
+		return rv.Type().Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
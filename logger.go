@@ -3,8 +3,10 @@ package clog
 import (
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"sync"
@@ -29,6 +31,10 @@ func (l Level) String() string {
 	return levelText[l]
 }
 
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
 const (
 	_ Level = iota - 1
 	LevelTrace
@@ -139,10 +145,31 @@ type Logger struct {
 	mu            sync.Mutex
 	Writer        io.Writer
 	Level         Level
+	Components    map[string]Level
 	ShowLevelText bool
 	ShowCaller    bool
 	ShowTime      bool
+	Compact       bool
+	NoColor       bool
+	Collect       bool
+	ExitSummary   bool
 	TimeFormat    string
+	// Theme colors this logger's output instead of the package styles.
+	Theme        *Theme
+	Redactor     *Redactor
+	Filter       *Filter
+	Deduplicator *Deduplicator
+	Backtrace    *Backtrace
+	Sinks        []Sink
+	Formatter    Formatter
+	Escape       EscapeMode
+	Width        int
+	root         *Logger
+	region       *liveRegion
+	summary      *summary
+	indent       int
+	task         *TaskLogger
+	config       *configState
 }
 
 var logger = New()
@@ -154,7 +181,12 @@ func New() *Logger {
 		ShowLevelText: false,
 		ShowCaller:    false,
 		ShowTime:      false,
+		Compact:       false,
+		NoColor:       os.Getenv("NO_COLOR") != "",
 		TimeFormat:    "2006-01-02 15:04:05",
+		Redactor:      NewRedactor(),
+		Formatter:     &PrettyFormatter{},
+		Escape:        EscapeControl,
 	}
 }
 
@@ -170,6 +202,22 @@ func WithTimestamp(with bool) *Logger {
 	return logger.WithTimestamp(with)
 }
 
+func WithCompact(with bool) *Logger {
+	return logger.WithCompact(with)
+}
+
+func WithColor(with bool) *Logger {
+	return logger.WithColor(with)
+}
+
+func WithCollector(with bool) *Logger {
+	return logger.WithCollector(with)
+}
+
+func WithExitSummary(with bool) *Logger {
+	return logger.WithExitSummary(with)
+}
+
 func SetTimeFormat(timeFormat string) *Logger {
 	return logger.SetTimeFormat(timeFormat)
 }
@@ -182,6 +230,26 @@ func SetLogLevel(level Level) *Logger {
 	return logger.SetLogLevel(level)
 }
 
+func SetComponentLevel(component string, level Level) *Logger {
+	return logger.SetComponentLevel(component, level)
+}
+
+func SetRedactor(redactor *Redactor) *Logger {
+	return logger.SetRedactor(redactor)
+}
+
+func SetFormatter(formatter Formatter) *Logger {
+	return logger.SetFormatter(formatter)
+}
+
+func SetEscapeMode(mode EscapeMode) *Logger {
+	return logger.SetEscapeMode(mode)
+}
+
+func SetWidth(width int) *Logger {
+	return logger.SetWidth(width)
+}
+
 func (l *Logger) WithLevelText(with bool) *Logger {
 	l.ShowLevelText = with
 	return l
@@ -197,6 +265,20 @@ func (l *Logger) WithTimestamp(with bool) *Logger {
 	return l
 }
 
+// WithCompact renders fields inline after the message. Entries that do not
+// fit on one line still use the tree layout.
+func (l *Logger) WithCompact(with bool) *Logger {
+	l.Compact = with
+	return l
+}
+
+// WithColor(false) makes the pretty formatter write plain text, with ASCII
+// borders for banners and boxes. It is off by default when NO_COLOR is set.
+func (l *Logger) WithColor(with bool) *Logger {
+	l.NoColor = !with
+	return l
+}
+
 func (l *Logger) SetTimeFormat(timeFormat string) *Logger {
 	l.TimeFormat = timeFormat
 	return l
@@ -207,98 +289,262 @@ func (l *Logger) SetWriter(writer io.Writer) *Logger {
 	return l
 }
 
+// SetLogLevel sets the level below which entries are dropped. On a logger
+// loaded from a config, it changes the level shared with the loggers derived
+// from it, until the file is reloaded.
 func (l *Logger) SetLogLevel(level Level) *Logger {
+	if l.config != nil {
+		l.config.update(func(s *levelSettings) { s.level = level })
+		return l
+	}
 	l.Level = level
 	return l
 }
 
-func (l *Logger) getCallerInfo() (path string, line int) {
-	if !l.ShowCaller {
-		return
+// SetComponentLevel sets the level of the entries whose component field is
+// component, overriding the logger's level for them.
+func (l *Logger) SetComponentLevel(component string, level Level) *Logger {
+	set := func(components map[string]Level) map[string]Level {
+		components = maps.Clone(components)
+		if components == nil {
+			components = map[string]Level{}
+		}
+		components[component] = level
+		return components
 	}
+	if l.config != nil {
+		l.config.update(func(s *levelSettings) { s.components = set(s.components) })
+		return l
+	}
+	l.Components = set(l.Components)
+	return l
+}
 
-	_, path, line, _ = runtime.Caller(3)
-	_, callerBase, _, _ := runtime.Caller(0)
-	basepath := filepath.Dir(callerBase)
-	basepath = strings.ReplaceAll(basepath, "\\", "/")
-	path = strings.TrimPrefix(path, basepath)
-	return
+func (l *Logger) SetRedactor(redactor *Redactor) *Logger {
+	l.Redactor = redactor
+	return l
 }
 
-func (l *Logger) renderTimestamp() string {
-	if !l.ShowTime {
-		return ""
+func (l *Logger) SetFormatter(formatter Formatter) *Logger {
+	l.Formatter = formatter
+	return l
+}
+
+func (l *Logger) SetEscapeMode(mode EscapeMode) *Logger {
+	l.Escape = mode
+	return l
+}
+
+// SetWidth fixes the output width used for wrapping. Zero detects the
+// terminal width, a negative width disables wrapping.
+func (l *Logger) SetWidth(width int) *Logger {
+	l.Width = width
+	return l
+}
+
+// clone returns a copy of the logger that shares its output lock, live
+// region and summary, for scoped loggers writing to the same destination.
+// The fields are copied one by one, as the lock itself must not be.
+func (l *Logger) clone() *Logger {
+	return &Logger{
+		Writer:        l.Writer,
+		Level:         l.Level,
+		Components:    l.Components,
+		ShowLevelText: l.ShowLevelText,
+		ShowCaller:    l.ShowCaller,
+		ShowTime:      l.ShowTime,
+		Compact:       l.Compact,
+		NoColor:       l.NoColor,
+		Collect:       l.Collect,
+		ExitSummary:   l.ExitSummary,
+		TimeFormat:    l.TimeFormat,
+		Theme:         l.Theme,
+		Redactor:      l.Redactor,
+		Filter:        l.Filter,
+		Deduplicator:  l.Deduplicator,
+		Backtrace:     l.Backtrace,
+		Sinks:         l.Sinks,
+		Formatter:     l.Formatter,
+		Escape:        l.Escape,
+		Width:         l.Width,
+		root:          l.shared(),
+		indent:        l.indent,
+		task:          l.task,
+		config:        l.config,
 	}
-	return fmt.Sprintf("%s %s ",
-		lipgloss.NewStyle().Foreground(gray).Render(time.Now().Format(l.TimeFormat)),
-		divide.Render(),
-	)
 }
 
-func (l *Logger) renderLevelText(level Level) string {
-	if !l.ShowLevelText {
-		return ""
+// shared returns the logger whose lock, live region and summary l uses: the
+// one it was cloned from, or l itself. A zero Logger is ready to use, as
+// the region and summary are made on first use.
+func (l *Logger) shared() *Logger {
+	if l.root != nil {
+		return l.root
 	}
-	text, style := strings.ToUpper(level.String()), Styles[level]
-	return fmt.Sprintf("%s%s ",
-		style.Text.Foreground(style.Color).Width(8).SetString(text).Render(),
-		divide.Render(),
-	)
+	return l
 }
 
-func (l *Logger) print(level Level, msg string, e *Entry) {
-	if level < l.Level {
+func (l *Logger) lock() {
+	l.shared().mu.Lock()
+}
+
+func (l *Logger) unlock() {
+	l.shared().mu.Unlock()
+}
+
+// regionState returns the shared live region; the lock must be held.
+func (l *Logger) regionState() *liveRegion {
+	root := l.shared()
+	if root.region == nil {
+		root.region = &liveRegion{}
+	}
+	return root.region
+}
+
+// summaryState returns the shared summary; the lock must be held.
+func (l *Logger) summaryState() *summary {
+	root := l.shared()
+	if root.summary == nil {
+		root.summary = &summary{}
+	}
+	return root.summary
+}
+
+// formatter returns the Formatter, which is the pretty one when unset.
+func (l *Logger) formatter() Formatter {
+	if l.Formatter == nil {
+		return defaultFormatter
+	}
+	return l.Formatter
+}
+
+var defaultFormatter = &PrettyFormatter{}
+
+func (l *Logger) width() int {
+	if l.Width != 0 {
+		return max(l.Width, 0)
+	}
+	return terminalWidth(l.Writer)
+}
+
+var packagePath = reflect.TypeOf(Logger{}).PkgPath()
+
+func (l *Logger) getCallerInfo() (path string, line int) {
+	if !l.ShowCaller {
 		return
 	}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	// the first frame outside this package, so helpers that log on the
+	// caller's behalf (tasks, spinners, package-level wrappers) are skipped
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePath+".") || !more {
+			path, line = frame.File, frame.Line
+			break
+		}
+	}
+	_, callerBase, _, _ := runtime.Caller(0)
+	basepath := filepath.Dir(callerBase)
+	basepath = strings.ReplaceAll(basepath, "\\", "/")
+	path = strings.TrimPrefix(path, basepath)
+	return
+}
 
-	style := Styles[level]
-	timestamp := l.renderTimestamp()
+func (l *Logger) print(e *Entry) {
+	settings := l.settings()
+	level := settings.levelOf(e)
+	if e.Level < level && l.Backtrace == nil {
+		return
+	}
 
-	_, _ = fmt.Fprint(l.Writer,
-		timestamp,
-		style.Icon.Foreground(style.Color).Render(""),
-		l.renderLevelText(level),
-		style.Message.Foreground(style.Color).Render(msg),
-	)
+	l.lock()
+	defer l.unlock()
 
+	// entries read back from another process keep their time and caller
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if l.ShowCaller && e.Caller == "" {
+		path, line := l.getCallerInfo()
+		e.Caller = fmt.Sprintf("%s:%d", path, line)
+	}
 	if e.Error != nil {
 		e.Any("err", e.Error)
 	}
-	if l.ShowCaller {
-		path, line := l.getCallerInfo()
-		e.Any("caller", lipgloss.NewStyle().Foreground(gray).Render(fmt.Sprintf("%s:%d", path, line)))
+	// task names, titles, labels and parsed lines reach here unescaped
+	if !e.escaped {
+		e.Message = l.sanitize(e.Message)
 	}
-	i := 0
-	for it := e.Fields.Front(); it != nil; it = it.Next() {
-		key, value := it.Key, it.Value
-		i++
-		keyStyle := style.Key.Copy().Foreground(style.Color)
-		if key == "caller" {
-			keyStyle.Foreground(gray)
-		}
-		if value == nil {
-			value = ""
+	e.Caller = l.sanitize(e.Caller)
+	l.resolve(e)
+	// the filter sees the fields as logged, a redacted one would never match
+	if !settings.filter.Match(e) {
+		return
+	}
+	e.Message = l.redact(e.Message, e)
+
+	if e.Level < level {
+		l.Backtrace.add(e)
+		return
+	}
+	if l.Backtrace != nil && e.Level >= l.Backtrace.Trigger {
+		for _, entry := range l.Backtrace.drain() {
+			entry.Backfilled = true
+			l.emit(entry)
 		}
-		value = fmt.Sprint(value)
-		if key != "" && value != "" {
-			key += ": "
+	}
+	l.emit(e)
+}
+
+// levelSettings decide which entries are logged. A logger loaded from a
+// config and the loggers derived from it share the config's, which a reload
+// replaces as a whole, so they are read without the lock.
+type levelSettings struct {
+	level      Level
+	components map[string]Level
+	filter     *Filter
+}
+
+func (l *Logger) settings() levelSettings {
+	if l.config != nil {
+		if s := l.config.levels.Load(); s != nil {
+			return *s
 		}
-		argPrefix := "├─"
-		if i == e.Fields.Len() {
-			argPrefix = "└─"
+	}
+	return levelSettings{level: l.Level, components: l.Components, filter: l.Filter}
+}
+
+// levelOf returns the level entries like e are logged from: the level of
+// their component, when there is one, or the logger's.
+func (s levelSettings) levelOf(e *Entry) Level {
+	if len(s.components) > 0 {
+		if component, ok := e.Fields.Get("component"); ok {
+			if level, ok := s.components[fmt.Sprint(component)]; ok {
+				return level
+			}
 		}
-		_, _ = fmt.Fprintf(l.Writer,
-			"\n  %s %s%s",
-			lipgloss.NewStyle().Foreground(gray).Faint(true).Render(argPrefix),
-			keyStyle.Render(key),
-			value,
-		)
 	}
+	return s.level
+}
 
-	_, _ = fmt.Fprintln(l.Writer)
+// enabled reports whether entries at level pass the logger's level.
+func (l *Logger) enabled(level Level) bool {
+	return level >= l.settings().level
+}
+
+// emit writes an entry that passed the level; the lock must be held.
+func (l *Logger) emit(e *Entry) {
+	if e.Kind != KindSummary {
+		l.summaryState().add(e, l.Collect)
+	}
+	l.writeSinks(e)
+	if l.Deduplicator != nil {
+		l.Deduplicator.write(l, e)
+		return
+	}
+	l.write(l.formatter().Format(e))
 }
 
 func (l *Logger) newEntry(level Level) *Entry {
@@ -307,6 +553,12 @@ func (l *Logger) newEntry(level Level) *Entry {
 	return e
 }
 
+// Log logs an entry built beforehand, such as one read by ParseLine, with
+// its message as it is. Unlike Msg, it does not exit on fatal entries.
+func (l *Logger) Log(e *Entry) {
+	l.print(e)
+}
+
 func (l *Logger) Trace() *Entry {
 	return l.newEntry(LevelTrace)
 }
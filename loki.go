@@ -0,0 +1,209 @@
+package clog
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LokiSink pushes entries to Grafana Loki. Entries are grouped into streams
+// by their labels: the static labels plus the fields named in Labels, where
+// "level" is the entry's level. Those fields are left out of the line,
+// which carries the message and the other fields in logfmt.
+type LokiSink struct {
+	Batching
+	URL          string
+	TenantID     string
+	Labels       []string
+	StaticLabels map[string]string
+	Protobuf     bool
+	Headers      map[string]string
+	Client       *http.Client
+
+	batcher batcher[lokiEntry]
+}
+
+type lokiEntry struct {
+	labels string
+	stream map[string]string
+	time   time.Time
+	line   string
+}
+
+// NewLokiSink pushes to the Loki instance at url, which may be the address
+// of the server or the full push endpoint.
+func NewLokiSink(url string) *LokiSink {
+	if !strings.Contains(url, "/loki/api/") {
+		url = strings.TrimRight(url, "/") + "/loki/api/v1/push"
+	}
+	hostname, _ := os.Hostname()
+	return &LokiSink{
+		Batching: defaultBatching(),
+		URL:      url,
+		Labels:   []string{"level"},
+		StaticLabels: map[string]string{
+			"job":  filepath.Base(os.Args[0]),
+			"host": hostname,
+		},
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *LokiSink) WriteEntry(e *Entry) error {
+	labels := make(map[string]string, len(s.StaticLabels)+len(s.Labels))
+	for name, value := range s.StaticLabels {
+		labels[lokiLabelName(name)] = value
+	}
+	isLabel := make(map[string]bool, len(s.Labels))
+	for _, name := range s.Labels {
+		isLabel[name] = true
+		if name == "level" {
+			labels["level"] = e.Level.String()
+		}
+	}
+
+	var line bytes.Buffer
+	if !isLabel["level"] {
+		writeLogfmtField(&line, "level", e.Level.String())
+	}
+	writeLogfmtField(&line, "msg", e.Message)
+	for _, field := range sinkFields(e) {
+		value := field.Value.(string)
+		if isLabel[field.Key] {
+			labels[lokiLabelName(field.Key)] = value
+			continue
+		}
+		writeLogfmtField(&line, logfmtKey(field.Key), value)
+	}
+
+	s.batcher.add(s.Batching, lokiEntry{labels: lokiLabels(labels), stream: labels, time: e.Time, line: line.String()}, s.send)
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (s *LokiSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// Close pushes the queued entries and stops the sink.
+func (s *LokiSink) Close() error {
+	s.batcher.close()
+	return nil
+}
+
+func (s *LokiSink) send(entries []lokiEntry) error {
+	streams := map[string][]lokiEntry{}
+	var order []string
+	for _, entry := range entries {
+		if _, ok := streams[entry.labels]; !ok {
+			order = append(order, entry.labels)
+		}
+		streams[entry.labels] = append(streams[entry.labels], entry)
+	}
+	for _, labels := range order {
+		sort.SliceStable(streams[labels], func(i, j int) bool {
+			return streams[labels][i].time.Before(streams[labels][j].time)
+		})
+	}
+
+	headers := make(map[string]string, len(s.Headers)+2)
+	for key, value := range s.Headers {
+		headers[key] = value
+	}
+	if s.TenantID != "" {
+		headers["X-Scope-OrgID"] = s.TenantID
+	}
+
+	if s.Protobuf {
+		headers["Content-Encoding"] = "snappy"
+		return postHTTP(s.Client, s.URL, "application/x-protobuf", headers, snappyEncode(lokiProto(order, streams)))
+	}
+	body, err := lokiJSON(order, streams)
+	if err != nil {
+		return err
+	}
+	return postHTTP(s.Client, s.URL, "application/json", headers, body)
+}
+
+// lokiProto encodes a logproto.PushRequest.
+func lokiProto(order []string, streams map[string][]lokiEntry) []byte {
+	var w protoWriter
+	for _, labels := range order {
+		w.message(1, func(w *protoWriter) {
+			w.string(1, labels)
+			for _, entry := range streams[labels] {
+				w.message(2, func(w *protoWriter) {
+					w.message(1, func(w *protoWriter) {
+						w.varint(1, uint64(entry.time.Unix()))
+						w.varint(2, uint64(entry.time.Nanosecond()))
+					})
+					w.string(2, entry.line)
+				})
+			}
+		})
+	}
+	return w.b
+}
+
+func lokiJSON(order []string, streams map[string][]lokiEntry) ([]byte, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	body := struct {
+		Streams []stream `json:"streams"`
+	}{}
+	for _, labels := range order {
+		st := stream{Stream: streams[labels][0].stream}
+		for _, entry := range streams[labels] {
+			st.Values = append(st.Values, [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), entry.line})
+		}
+		body.Streams = append(body.Streams, st)
+	}
+	return marshalJSON(body)
+}
+
+var lokiValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// lokiLabels renders labels in the {name="value", ...} form that identifies
+// a stream, sorted by name.
+func lokiLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(lokiValueReplacer.Replace(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// lokiLabelName returns a valid label name: letters, digits and
+// underscores, not starting with a digit.
+func lokiLabelName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,123 @@
+package clog
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func lokiSink(url string) *LokiSink {
+	s := NewLokiSink(url)
+	s.TenantID = "team"
+	s.Labels = []string{"level", "service"}
+	s.StaticLabels = map[string]string{"job": "test"}
+	return s
+}
+
+// writeLokiEntries logs two streams, with the second entry of the api
+// stream logged before the first, to check that streams are sorted.
+func writeLokiEntries(s *LokiSink) {
+	for _, entry := range []struct {
+		level   Level
+		seconds int64
+		service string
+		msg     string
+	}{
+		{LevelInfo, 2, "api", "second"},
+		{LevelError, 3, "db", "down"},
+		{LevelInfo, 1, "api", "first"},
+	} {
+		e := testEntry(entry.level, entry.msg)
+		e.Time = time.Unix(entry.seconds, 7)
+		e.Any("service", entry.service).Any("user", "ann")
+		s.WriteEntry(e)
+	}
+	s.Close()
+}
+
+func TestLokiJSON(t *testing.T) {
+	srv, requests := collector(t)
+	s := lokiSink(srv.URL)
+	if s.URL != srv.URL+"/loki/api/v1/push" {
+		t.Errorf("URL = %q", s.URL)
+	}
+	writeLokiEntries(s)
+
+	req := <-requests
+	if got := req.header.Get("X-Scope-OrgID"); got != "team" {
+		t.Errorf("X-Scope-OrgID = %q", got)
+	}
+	var body, want any
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal([]byte(`{"streams": [
+		{"stream": {"job": "test", "level": "info", "service": "api"}, "values": [
+			["1000000007", "msg=first user=ann"],
+			["2000000007", "msg=second user=ann"]
+		]},
+		{"stream": {"job": "test", "level": "error", "service": "db"}, "values": [
+			["3000000007", "msg=down user=ann"]
+		]}
+	]}`), &want)
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("got %s", req.body)
+	}
+}
+
+func TestLokiProtobuf(t *testing.T) {
+	srv, requests := collector(t)
+	s := lokiSink(srv.URL)
+	s.Protobuf = true
+	writeLokiEntries(s)
+
+	req := <-requests
+	if got := req.header.Get("Content-Encoding"); got != "snappy" {
+		t.Errorf("Content-Encoding = %q", got)
+	}
+	if got := req.header.Get("Content-Type"); got != "application/x-protobuf" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	data, err := snappyDecode(req.body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		seconds, nanos uint64
+		line           string
+	}
+	want := map[string][]entry{
+		`{job="test", level="info", service="api"}`: {{1, 7, "msg=first user=ann"}, {2, 7, "msg=second user=ann"}},
+		`{job="test", level="error", service="db"}`: {{3, 7, "msg=down user=ann"}},
+	}
+	var order []string
+	got := map[string][]entry{}
+	for _, stream := range decodeProto(t, data).messages(t, 1) {
+		labels := stream.string(1)
+		order = append(order, labels)
+		for _, e := range stream.messages(t, 2) {
+			ts := e.message(t, 1)
+			got[labels] = append(got[labels], entry{ts.uint(1), ts.uint(2), e.string(2)})
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got streams %v\nwant %v", got, want)
+	}
+	if len(order) != 2 || order[0] != `{job="test", level="info", service="api"}` {
+		t.Errorf("streams in order %q, want the first logged first", order)
+	}
+}
+
+func TestLokiLabels(t *testing.T) {
+	got := lokiLabels(map[string]string{"b": `say "hi"\n`, "a": "x\ny"})
+	if want := `{a="x\ny", b="say \"hi\"\\n"}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	for name, want := range map[string]string{"user.id": "user_id", "9lives": "_9lives"} {
+		if got := lokiLabelName(name); got != want {
+			t.Errorf("lokiLabelName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
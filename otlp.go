@@ -0,0 +1,386 @@
+package clog
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// otlpSeverity maps levels to OpenTelemetry severity numbers. Notice, Ok
+// and Success are informational, ranked above Info.
+var otlpSeverity = [...]int{
+	LevelTrace:   1,
+	LevelDebug:   5,
+	LevelInfo:    9,
+	LevelNotice:  10,
+	LevelWarn:    13,
+	LevelOk:      11,
+	LevelSuccess: 12,
+	LevelError:   17,
+	LevelFatal:   21,
+}
+
+type traceContextKey struct{}
+
+type traceContext struct {
+	traceID, spanID string
+}
+
+// ContextWithTrace returns a context carrying hex trace and span IDs, for
+// entries logged with Entry.Ctx when no tracing library provides them.
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext{traceID: traceID, spanID: spanID})
+}
+
+func traceFromContext(ctx context.Context) (traceID, spanID string) {
+	tc, _ := ctx.Value(traceContextKey{}).(traceContext)
+	return tc.traceID, tc.spanID
+}
+
+// OTLPExporter exports entries as OpenTelemetry log records over OTLP/HTTP,
+// JSON encoded or, with Protobuf set, in the binary encoding.
+type OTLPExporter struct {
+	Batching
+	Endpoint string
+	Protobuf bool
+	Headers  map[string]string
+	Client   *http.Client
+	// Resource holds the attributes of the process, service.name by default.
+	Resource Object
+	// SpanContext returns the hex trace and span IDs of the context passed
+	// to Entry.Ctx. By default it reads those set by ContextWithTrace.
+	SpanContext func(ctx context.Context) (traceID, spanID string)
+
+	batcher batcher[otlpRecord]
+}
+
+type otlpRecord struct {
+	time     time.Time
+	severity int
+	level    string
+	body     string
+	attrs    Object
+	traceID  []byte
+	spanID   []byte
+}
+
+func NewOTLPExporter(endpoint string) *OTLPExporter {
+	if endpoint == "" {
+		endpoint = "http://localhost:4318/v1/logs"
+	}
+	return &OTLPExporter{
+		Batching:    defaultBatching(),
+		Endpoint:    endpoint,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		Resource:    Object{{Key: "service.name", Value: filepath.Base(os.Args[0])}},
+		SpanContext: traceFromContext,
+	}
+}
+
+func (x *OTLPExporter) WriteEntry(e *Entry) error {
+	l := e.Logger
+	r := otlpRecord{
+		time:  e.Time,
+		level: e.Level.String(),
+		body:  e.Message,
+	}
+	if e.Level >= 0 && int(e.Level) < len(otlpSeverity) {
+		r.severity = otlpSeverity[e.Level]
+	}
+	if i := strings.LastIndexByte(e.Caller, ':'); i >= 0 {
+		r.attrs.Add("code.filepath", e.Caller[:i])
+		if line, err := strconv.ParseInt(e.Caller[i+1:], 10, 64); err == nil {
+			r.attrs.Add("code.lineno", line)
+		}
+	}
+	for _, arg := range eventFields(e) {
+		r.attrs.Add(arg.Key, otlpValue(l, arg.Value))
+	}
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		r.attrs.Add(l.sanitize(it.Key), otlpValue(l, it.Value))
+	}
+	if e.Context != nil && x.SpanContext != nil {
+		traceID, spanID := x.SpanContext(e.Context)
+		r.traceID, _ = hex.DecodeString(traceID)
+		r.spanID, _ = hex.DecodeString(spanID)
+	}
+	x.batcher.add(x.Batching, r, x.send)
+	return nil
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (x *OTLPExporter) Dropped() uint64 {
+	return x.batcher.dropped.Load()
+}
+
+// Close exports the queued records and stops the exporter.
+func (x *OTLPExporter) Close() error {
+	x.batcher.close()
+	return nil
+}
+
+func (x *OTLPExporter) send(records []otlpRecord) error {
+	if x.Protobuf {
+		return postHTTP(x.Client, x.Endpoint, "application/x-protobuf", x.Headers, x.encodeProto(records))
+	}
+	body, err := x.encodeJSON(records)
+	if err != nil {
+		return err
+	}
+	return postHTTP(x.Client, x.Endpoint, "application/json", x.Headers, body)
+}
+
+// otlpValue converts a resolved field value to one of the types of an OTLP
+// AnyValue: string, bool, int64, float64, []byte, []any or Object.
+func otlpValue(l *Logger, value any) any {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case Object:
+		obj := make(Object, len(value))
+		for i, arg := range value {
+			obj[i] = Argument{Key: l.sanitize(arg.Key), Value: otlpValue(l, arg.Value)}
+		}
+		return obj
+	case Table:
+		list := make([]any, 0, len(value.Rows))
+		for _, obj := range value.Objects() {
+			list = append(list, otlpValue(l, obj))
+		}
+		return list
+	case []byte:
+		return value
+	}
+	if isComposite(value) {
+		data, err := marshalJSON(l.jsonValue(value))
+		if err != nil {
+			return l.valueString(value)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var decoded any
+		if dec.Decode(&decoded) != nil {
+			return l.valueString(value)
+		}
+		return otlpJSONValue(decoded)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, ok := value.(time.Duration); !ok {
+			return rv.Int()
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// values past the int64 range would wrap around
+		if n := rv.Uint(); n <= math.MaxInt64 {
+			return int64(n)
+		}
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		// OTLP/JSON has no NaN or infinities, send them as "NaN", "+Inf"
+		// and "-Inf" in both encodings
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		return f
+	}
+	return l.valueString(value)
+}
+
+func otlpJSONValue(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		f, _ := value.Float64()
+		return f
+	case []any:
+		for i := range value {
+			value[i] = otlpJSONValue(value[i])
+		}
+		return value
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		obj := make(Object, len(keys))
+		for i, key := range keys {
+			obj[i] = Argument{Key: key, Value: otlpJSONValue(value[key])}
+		}
+		return obj
+	}
+	return value
+}
+
+func (x *OTLPExporter) encodeProto(records []otlpRecord) []byte {
+	var w protoWriter
+	// ExportLogsServiceRequest.resource_logs
+	w.message(1, func(w *protoWriter) {
+		// ResourceLogs.resource
+		w.message(1, func(w *protoWriter) {
+			for _, arg := range x.Resource {
+				w.message(1, func(w *protoWriter) { protoKeyValue(w, arg.Key, arg.Value) })
+			}
+		})
+		// ResourceLogs.scope_logs
+		w.message(2, func(w *protoWriter) {
+			w.message(1, func(w *protoWriter) { w.string(1, "clog") })
+			for _, r := range records {
+				w.message(2, r.encodeProto)
+			}
+		})
+	})
+	return w.b
+}
+
+func (r otlpRecord) encodeProto(w *protoWriter) {
+	w.fixed64(1, uint64(r.time.UnixNano()))
+	w.varint(2, uint64(r.severity))
+	w.string(3, r.level)
+	w.message(5, func(w *protoWriter) { protoAnyValue(w, r.body) })
+	for _, arg := range r.attrs {
+		w.message(6, func(w *protoWriter) { protoKeyValue(w, arg.Key, arg.Value) })
+	}
+	if len(r.traceID) == 16 {
+		w.bytes(9, r.traceID)
+	}
+	if len(r.spanID) == 8 {
+		w.bytes(10, r.spanID)
+	}
+	w.fixed64(11, uint64(r.time.UnixNano()))
+}
+
+func protoKeyValue(w *protoWriter, key string, value any) {
+	w.string(1, key)
+	w.message(2, func(w *protoWriter) { protoAnyValue(w, value) })
+}
+
+func protoAnyValue(w *protoWriter, value any) {
+	switch value := value.(type) {
+	case string:
+		w.string(1, value)
+	case bool:
+		v := uint64(0)
+		if value {
+			v = 1
+		}
+		w.varint(2, v)
+	case int64:
+		w.varint(3, uint64(value))
+	case float64:
+		w.double(4, value)
+	case []any:
+		w.message(5, func(w *protoWriter) {
+			for _, item := range value {
+				w.message(1, func(w *protoWriter) { protoAnyValue(w, item) })
+			}
+		})
+	case Object:
+		w.message(6, func(w *protoWriter) {
+			for _, arg := range value {
+				w.message(1, func(w *protoWriter) { protoKeyValue(w, arg.Key, arg.Value) })
+			}
+		})
+	case []byte:
+		w.bytes(7, value)
+	case nil:
+	default:
+		w.string(1, fmt.Sprint(value))
+	}
+}
+
+type otlpJSONKeyValue struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+type otlpJSONRecord struct {
+	TimeUnixNano         string             `json:"timeUnixNano"`
+	ObservedTimeUnixNano string             `json:"observedTimeUnixNano"`
+	SeverityNumber       int                `json:"severityNumber"`
+	SeverityText         string             `json:"severityText"`
+	Body                 any                `json:"body"`
+	Attributes           []otlpJSONKeyValue `json:"attributes,omitempty"`
+	TraceID              string             `json:"traceId,omitempty"`
+	SpanID               string             `json:"spanId,omitempty"`
+}
+
+func (x *OTLPExporter) encodeJSON(records []otlpRecord) ([]byte, error) {
+	logRecords := make([]otlpJSONRecord, len(records))
+	for i, r := range records {
+		nanos := strconv.FormatInt(r.time.UnixNano(), 10)
+		logRecords[i] = otlpJSONRecord{
+			TimeUnixNano:         nanos,
+			ObservedTimeUnixNano: nanos,
+			SeverityNumber:       r.severity,
+			SeverityText:         r.level,
+			Body:                 jsonAnyValue(r.body),
+			Attributes:           jsonKeyValues(r.attrs),
+			TraceID:              hex.EncodeToString(r.traceID),
+			SpanID:               hex.EncodeToString(r.spanID),
+		}
+	}
+	return marshalJSON(map[string]any{
+		"resourceLogs": []any{map[string]any{
+			"resource": map[string]any{"attributes": jsonKeyValues(x.Resource)},
+			"scopeLogs": []any{map[string]any{
+				"scope":      map[string]any{"name": "clog"},
+				"logRecords": logRecords,
+			}},
+		}},
+	})
+}
+
+func jsonKeyValues(obj Object) []otlpJSONKeyValue {
+	kvs := make([]otlpJSONKeyValue, len(obj))
+	for i, arg := range obj {
+		kvs[i] = otlpJSONKeyValue{Key: arg.Key, Value: jsonAnyValue(arg.Value)}
+	}
+	return kvs
+}
+
+// jsonAnyValue encodes an AnyValue the way OTLP/JSON does, with 64 bit
+// integers as strings.
+func jsonAnyValue(value any) any {
+	switch value := value.(type) {
+	case string:
+		return map[string]any{"stringValue": value}
+	case bool:
+		return map[string]any{"boolValue": value}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		return map[string]any{"doubleValue": value}
+	case []byte:
+		return map[string]any{"bytesValue": value}
+	case []any:
+		values := make([]any, len(value))
+		for i, item := range value {
+			values[i] = jsonAnyValue(item)
+		}
+		return map[string]any{"arrayValue": map[string]any{"values": values}}
+	case Object:
+		return map[string]any{"kvlistValue": map[string]any{"values": jsonKeyValues(value)}}
+	case nil:
+		return map[string]any{}
+	}
+	return map[string]any{"stringValue": fmt.Sprint(value)}
+}
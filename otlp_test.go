@@ -0,0 +1,197 @@
+package clog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// collector serves the status codes in order, then 200, and passes every
+// request it receives on.
+func collector(t *testing.T, statuses ...int) (*httptest.Server, <-chan capturedRequest) {
+	t.Helper()
+	requests := make(chan capturedRequest, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- capturedRequest{header: r.Header, body: body}
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+func TestOTLPJSON(t *testing.T) {
+	srv, requests := collector(t)
+	x := NewOTLPExporter(srv.URL)
+	x.Resource = Object{{Key: "service.name", Value: "test"}}
+	x.Headers = map[string]string{"Authorization": "Bearer x"}
+	e := testEntry(LevelWarn, "disk low")
+	e.Caller = "main.go:42"
+	e.Any("free", 12).Any("ok", true).Any("ratio", 0.5).
+		Any("disk", Object{{Key: "name", Value: "sda"}})
+	e.Context = ContextWithTrace(context.Background(),
+		"0102030405060708090a0b0c0d0e0f10", "a1a2a3a4a5a6a7a8")
+	x.WriteEntry(e)
+	x.Close()
+
+	req := <-requests
+	if got := req.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := req.header.Get("Authorization"); got != "Bearer x" {
+		t.Errorf("Authorization = %q", got)
+	}
+	var body any
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	var want any
+	json.Unmarshal([]byte(`{"resourceLogs": [{
+		"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "test"}}]},
+		"scopeLogs": [{
+			"scope": {"name": "clog"},
+			"logRecords": [{
+				"timeUnixNano": "1714564800000000000",
+				"observedTimeUnixNano": "1714564800000000000",
+				"severityNumber": 13,
+				"severityText": "warn",
+				"body": {"stringValue": "disk low"},
+				"attributes": [
+					{"key": "code.filepath", "value": {"stringValue": "main.go"}},
+					{"key": "code.lineno", "value": {"intValue": "42"}},
+					{"key": "free", "value": {"intValue": "12"}},
+					{"key": "ok", "value": {"boolValue": true}},
+					{"key": "ratio", "value": {"doubleValue": 0.5}},
+					{"key": "disk", "value": {"kvlistValue": {"values": [
+						{"key": "name", "value": {"stringValue": "sda"}}
+					]}}}
+				],
+				"traceId": "0102030405060708090a0b0c0d0e0f10",
+				"spanId": "a1a2a3a4a5a6a7a8"
+			}]
+		}]
+	}]}`), &want)
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("got %s", req.body)
+	}
+}
+
+func TestOTLPProtobuf(t *testing.T) {
+	srv, requests := collector(t)
+	x := NewOTLPExporter(srv.URL)
+	x.Protobuf = true
+	x.Resource = Object{{Key: "service.name", Value: "test"}}
+	e := testEntry(LevelWarn, "disk low")
+	e.Caller = "main.go:42"
+	e.Any("free", 12).Any("ok", true).Any("ratio", 0.5).
+		Any("disk", Object{{Key: "name", Value: "sda"}})
+	e.Context = ContextWithTrace(context.Background(),
+		"0102030405060708090a0b0c0d0e0f10", "a1a2a3a4a5a6a7a8")
+	x.WriteEntry(e)
+	x.Close()
+
+	req := <-requests
+	if got := req.header.Get("Content-Type"); got != "application/x-protobuf" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	resourceLogs := decodeProto(t, req.body).message(t, 1)
+	resource := resourceLogs.message(t, 1).message(t, 1)
+	if resource.string(1) != "service.name" || resource.message(t, 2).string(1) != "test" {
+		t.Errorf("resource attribute %v", resource)
+	}
+	scopeLogs := resourceLogs.message(t, 2)
+	if name := scopeLogs.message(t, 1).string(1); name != "clog" {
+		t.Errorf("scope name %q", name)
+	}
+	record := scopeLogs.message(t, 2)
+	if want := uint64(testTime.UnixNano()); record.uint(1) != want || record.uint(11) != want {
+		t.Errorf("times %d, %d", record.uint(1), record.uint(11))
+	}
+	if record.uint(2) != 13 || record.string(3) != "warn" {
+		t.Errorf("severity %d %q", record.uint(2), record.string(3))
+	}
+	if body := record.message(t, 5).string(1); body != "disk low" {
+		t.Errorf("body %q", body)
+	}
+	if traceID := record.string(9); traceID != "\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f\x10" {
+		t.Errorf("trace id %x", traceID)
+	}
+	if spanID := record.string(10); spanID != "\xa1\xa2\xa3\xa4\xa5\xa6\xa7\xa8" {
+		t.Errorf("span id %x", spanID)
+	}
+
+	attrs := map[string]protoFields{}
+	var keys []string
+	for _, kv := range record.messages(t, 6) {
+		keys = append(keys, kv.string(1))
+		attrs[kv.string(1)] = kv.message(t, 2)
+	}
+	if want := []string{"code.filepath", "code.lineno", "free", "ok", "ratio", "disk"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("attribute keys %q, want %q", keys, want)
+	}
+	if attrs["code.filepath"].string(1) != "main.go" || attrs["code.lineno"].uint(3) != 42 ||
+		attrs["free"].uint(3) != 12 || attrs["ok"].uint(2) != 1 ||
+		math.Float64frombits(attrs["ratio"].uint(4)) != 0.5 {
+		t.Errorf("attributes %v", attrs)
+	}
+	disk := attrs["disk"].message(t, 6).message(t, 1)
+	if disk.string(1) != "name" || disk.message(t, 2).string(1) != "sda" {
+		t.Errorf("kvlist %v", disk)
+	}
+}
+
+func TestOTLPRetry(t *testing.T) {
+	srv, requests := collector(t, http.StatusServiceUnavailable, http.StatusBadRequest)
+	var errs []error
+	x := NewOTLPExporter(srv.URL)
+	x.RetryBackoff = time.Millisecond
+	x.OnError = func(err error) { errs = append(errs, err) }
+	x.WriteEntry(testEntry(LevelWarn, "disk low"))
+	x.Close()
+
+	// 503 is retried, 400 is not
+	if n := len(requests); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+	if len(errs) != 1 {
+		t.Errorf("got errors %v, want one", errs)
+	}
+}
+
+func TestOTLPValue(t *testing.T) {
+	l := New()
+	for _, tt := range []struct {
+		value, want any
+	}{
+		{uint64(42), int64(42)},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{math.NaN(), "NaN"},
+		{math.Inf(1), "+Inf"},
+		{float32(math.Inf(-1)), "-Inf"},
+		{2.5, 2.5},
+	} {
+		if got := otlpValue(l, tt.value); got != tt.want {
+			t.Errorf("otlpValue(%v) = %#v, want %#v", tt.value, got, tt.want)
+		}
+	}
+
+	// the JSON encoding cannot hold what is sent as a string
+	body, err := json.Marshal(jsonAnyValue(otlpValue(l, math.NaN())))
+	if err != nil || string(body) != `{"stringValue":"NaN"}` {
+		t.Errorf("got %s, %v", body, err)
+	}
+}
@@ -0,0 +1,311 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// levelAliases are the names other loggers and syslog give to levels.
+var levelAliases = map[string]Level{
+	"warning":   LevelWarn,
+	"err":       LevelError,
+	"dpanic":    LevelError,
+	"panic":     LevelFatal,
+	"critical":  LevelFatal,
+	"crit":      LevelFatal,
+	"alert":     LevelFatal,
+	"emerg":     LevelFatal,
+	"emergency": LevelFatal,
+}
+
+func ParseLevel(s string) (Level, error) {
+	for level, text := range levelText {
+		if strings.EqualFold(s, text) {
+			return Level(level), nil
+		}
+	}
+	if level, ok := levelAliases[strings.ToLower(s)]; ok {
+		return level, nil
+	}
+	return LevelInfo, fmt.Errorf("clog: unknown level %q", s)
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// ParseLine reads an entry from a line of JSON or logfmt, as ParseJSON and
+// ParseLogfmt do.
+func (l *Logger) ParseLine(line []byte) (*Entry, error) {
+	if line = bytes.TrimSpace(line); bytes.HasPrefix(line, []byte("{")) {
+		return l.ParseJSON(line)
+	}
+	return l.ParseLogfmt(line)
+}
+
+// ParseJSON reads an entry from a line of JSON, as written by the JSON
+// formatter or by zap, zerolog, slog, logrus or bunyan, for logging it
+// again through l. The time, level, message and caller are read from the
+// keys these use, other keys become fields in their original order.
+func (l *Logger) ParseJSON(line []byte) (*Entry, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	value, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := value.(Object)
+	if !ok {
+		return nil, fmt.Errorf("clog: entry is not a JSON object")
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("clog: data after the entry")
+	}
+	return l.entryFromObject(obj), nil
+}
+
+// ParseLogfmt reads an entry from a line of logfmt, the way ParseJSON does.
+// Values are kept as strings.
+func (l *Logger) ParseLogfmt(line []byte) (*Entry, error) {
+	var obj Object
+	s := strings.TrimSpace(string(line))
+	for s != "" {
+		i := strings.IndexAny(s, "= ")
+		if i <= 0 || s[i] != '=' {
+			return nil, fmt.Errorf("clog: not a logfmt line")
+		}
+		key, value := s[:i], s[i+1:]
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("clog: logfmt value of %s: %w", key, err)
+			}
+			s = value[len(quoted):]
+			value, _ = strconv.Unquote(quoted)
+		} else {
+			end := strings.IndexByte(value, ' ')
+			if end < 0 {
+				end = len(value)
+			}
+			value, s = value[:end], value[end:]
+		}
+		if s != "" && s[0] != ' ' {
+			return nil, fmt.Errorf("clog: logfmt value of %s: unexpected %q", key, s[0])
+		}
+		s = strings.TrimLeft(s, " ")
+		obj.Add(key, value)
+	}
+	if len(obj) == 0 {
+		return nil, fmt.Errorf("clog: not a logfmt line")
+	}
+	return l.entryFromObject(obj), nil
+}
+
+// The keys logging libraries use, in order of preference.
+var (
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp", "t"}
+	levelKeys   = []string{"level", "lvl", "severity", "log.level"}
+	messageKeys = []string{"msg", "message"}
+	callerKeys  = []string{"caller", "source"}
+)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+func (l *Logger) entryFromObject(obj Object) *Entry {
+	e := l.newEntry(LevelInfo)
+	var hasTime, hasLevel, hasMessage, hasCaller bool
+	for _, arg := range obj {
+		switch {
+		case !hasTime && slices.Contains(timeKeys, arg.Key):
+			if t, ok := parseTime(arg.Value); ok {
+				e.Time, hasTime = t, true
+				continue
+			}
+		case !hasLevel && slices.Contains(levelKeys, arg.Key):
+			if level, ok := ParseLevelValue(arg.Value); ok {
+				e.Level, hasLevel = level, true
+				continue
+			}
+		case !hasMessage && slices.Contains(messageKeys, arg.Key):
+			if s, ok := arg.Value.(string); ok {
+				e.Message, hasMessage = s, true
+				continue
+			}
+		case !hasCaller && slices.Contains(callerKeys, arg.Key):
+			if caller, ok := parseCaller(arg.Value); ok {
+				e.Caller, hasCaller = caller, true
+				continue
+			}
+		}
+		e.Any(arg.Key, arg.Value)
+	}
+	return e
+}
+
+// parseTime reads a formatted time, or a Unix time in seconds or, for
+// larger numbers, in milli, micro or nanoseconds.
+func parseTime(value any) (time.Time, bool) {
+	var n float64
+	switch v := value.(type) {
+	case int64:
+		switch {
+		case v > 1e17:
+			return time.Unix(0, v), true
+		case v > 1e14:
+			return time.UnixMicro(v), true
+		case v > 1e11:
+			return time.UnixMilli(v), true
+		}
+		return time.Unix(v, 0), true
+	case float64:
+		n = v
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return t, true
+			}
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		n = f
+	default:
+		return time.Time{}, false
+	}
+	switch {
+	case n > 1e17:
+		return time.Unix(0, int64(n)), true
+	case n > 1e14:
+		return time.UnixMicro(int64(n)), true
+	case n > 1e11:
+		return time.UnixMilli(int64(n)), true
+	}
+	sec, frac := math.Modf(n)
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
+
+// ParseLevelValue reads the level of an entry written by another logger: a
+// level name, including slog's offsets such as WARN+2, or a bunyan or pino
+// level number, given as a string or an int64.
+func ParseLevelValue(value any) (Level, bool) {
+	s, ok := value.(string)
+	if !ok {
+		n, ok := value.(int64)
+		if !ok {
+			return LevelInfo, false
+		}
+		s = strconv.FormatInt(n, 10)
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		switch {
+		case n >= 60:
+			return LevelFatal, true
+		case n >= 50:
+			return LevelError, true
+		case n >= 40:
+			return LevelWarn, true
+		case n >= 30:
+			return LevelInfo, true
+		case n >= 20:
+			return LevelDebug, true
+		}
+		return LevelTrace, true
+	}
+	if i := strings.IndexAny(s, "+-"); i > 0 {
+		s = s[:i]
+	}
+	level, err := ParseLevel(s)
+	return level, err == nil
+}
+
+// parseCaller reads a file:line caller, or slog's source object.
+func parseCaller(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case Object:
+		var file string
+		var line any
+		for _, arg := range v {
+			switch arg.Key {
+			case "file":
+				file, _ = arg.Value.(string)
+			case "line":
+				line = arg.Value
+			}
+		}
+		if file == "" {
+			return "", false
+		}
+		if line == nil {
+			return file, true
+		}
+		return fmt.Sprintf("%s:%v", file, line), true
+	}
+	return "", false
+}
+
+// decodeOrdered decodes a JSON value with objects as Objects, keeping the
+// order of their keys, and numbers as int64 or float64.
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			var obj Object
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj.Add(key.(string), value)
+			}
+			_, err := dec.Token()
+			return obj, err
+		case '[':
+			list := []any{}
+			for dec.More() {
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			_, err := dec.Token()
+			return list, err
+		}
+	case json.Number:
+		if n, err := tok.Int64(); err == nil {
+			return n, nil
+		}
+		return tok.Float64()
+	}
+	return tok, nil
+}
@@ -0,0 +1,119 @@
+package clog
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name   string
+		line   string
+		level  Level
+		msg    string
+		caller string
+		fields []string
+	}{
+		{
+			"zap",
+			`{"level":"warn","ts":1714564800,"caller":"api/server.go:42","msg":"slow","took":"2s"}`,
+			LevelWarn, "slow", "api/server.go:42", []string{"took"},
+		},
+		{
+			"zerolog",
+			`{"level":"error","time":"2024-05-01T12:00:00Z","message":"failed","error":"refused"}`,
+			LevelError, "failed", "", []string{"error"},
+		},
+		{
+			"slog",
+			`{"time":"2024-05-01T12:00:00Z","level":"WARN+2","source":{"function":"main.run","file":"main.go","line":7},"msg":"retry"}`,
+			LevelWarn, "retry", "main.go:7", nil,
+		},
+		{
+			"logrus",
+			`{"level":"warning","msg":"disk low","time":"2024-05-01T12:00:00Z","free":12}`,
+			LevelWarn, "disk low", "", []string{"free"},
+		},
+		{
+			"bunyan",
+			`{"name":"app","hostname":"h","pid":1,"level":50,"msg":"down","time":"2024-05-01T12:00:00.000Z","v":0}`,
+			LevelError, "down", "", []string{"name", "hostname", "pid", "v"},
+		},
+		{
+			"logfmt",
+			`time=2024-05-01T12:00:00Z level=crit msg="out of memory" user=ann`,
+			LevelFatal, "out of memory", "", []string{"user"},
+		},
+	} {
+		e, err := New().ParseLine([]byte(tt.line))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if e.Level != tt.level || e.Message != tt.msg || e.Caller != tt.caller {
+			t.Errorf("%s: got %s %q %q, want %s %q %q",
+				tt.name, e.Level, e.Message, e.Caller, tt.level, tt.msg, tt.caller)
+		}
+		if !e.Time.Equal(at) {
+			t.Errorf("%s: got time %v", tt.name, e.Time)
+		}
+		if keys := e.Fields.Keys(); !slices.Equal(keys, tt.fields) {
+			t.Errorf("%s: got fields %q, want %q", tt.name, keys, tt.fields)
+		}
+	}
+}
+
+func TestParseLineRejects(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"plain text",
+		`{"msg":"a"} trailing`,
+		`[1, 2]`,
+		`key="unterminated`,
+		`key="a"b`,
+	} {
+		if e, err := New().ParseLine([]byte(line)); err == nil {
+			t.Errorf("%q: got entry %q, want an error", line, e.Message)
+		}
+	}
+}
+
+func TestParseTimeUnits(t *testing.T) {
+	want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, value := range []any{
+		int64(1714564800),
+		int64(1714564800000),
+		int64(1714564800000000),
+		int64(1714564800000000000),
+		1714564800.0,
+		"1714564800",
+	} {
+		if got, ok := parseTime(value); !ok || !got.Equal(want) {
+			t.Errorf("parseTime(%v) = %v, %v", value, got, ok)
+		}
+	}
+}
+
+func TestParseLevelValue(t *testing.T) {
+	for _, tt := range []struct {
+		value any
+		want  Level
+		ok    bool
+	}{
+		{"warning", LevelWarn, true},
+		{"ERROR", LevelError, true},
+		{"DEBUG-4", LevelDebug, true},
+		{"INFO+2", LevelInfo, true},
+		{int64(10), LevelTrace, true},
+		{int64(30), LevelInfo, true},
+		{"60", LevelFatal, true},
+		{"verbose", LevelInfo, false},
+		{true, LevelInfo, false},
+	} {
+		if got, ok := ParseLevelValue(tt.value); got != tt.want || ok != tt.ok {
+			t.Errorf("ParseLevelValue(%v) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
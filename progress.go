@@ -0,0 +1,325 @@
+package clog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type ProgressStyle struct {
+	Filled lipgloss.Style
+	Empty  lipgloss.Style
+	Label  lipgloss.Style
+	Info   lipgloss.Style
+}
+
+var (
+	ProgressStyles = ProgressStyle{
+		Filled: lipgloss.NewStyle().Foreground(lipgloss.Color("47")),
+		Empty:  lipgloss.NewStyle().Foreground(gray).Faint(true),
+		Label:  lipgloss.NewStyle().Bold(true),
+		Info:   lipgloss.NewStyle().Foreground(gray),
+	}
+	// ProgressInterval is how often a progress bar logs its state when the
+	// output is not a terminal and cannot be redrawn in place.
+	ProgressInterval = 5 * time.Second
+)
+
+const (
+	minBarWidth = 10
+	maxBarWidth = 40
+)
+
+// ProgressBar reports the progress of one operation. A total of zero or
+// less makes it indeterminate. It is safe for concurrent use, and as an
+// io.Writer it counts the bytes written through it.
+type ProgressBar struct {
+	logger  *Logger
+	group   *ProgressGroup
+	mu      sync.Mutex
+	label   string
+	current int64
+	total   int64
+	bytes   bool
+	start   time.Time
+	logged  time.Time
+	live    bool
+	done    bool
+}
+
+func Progress(label string, total int64) *ProgressBar {
+	return logger.Progress(label, total)
+}
+
+func ProgressBytes(label string, total int64) *ProgressBar {
+	return logger.ProgressBytes(label, total)
+}
+
+func (l *Logger) Progress(label string, total int64) *ProgressBar {
+	return l.newProgress(nil, label, total, false).begin()
+}
+
+func (l *Logger) ProgressBytes(label string, total int64) *ProgressBar {
+	return l.newProgress(nil, label, total, true).begin()
+}
+
+func (l *Logger) newProgress(group *ProgressGroup, label string, total int64, bytes bool) *ProgressBar {
+	now := time.Now()
+	return &ProgressBar{
+		logger: l,
+		group:  group,
+		label:  l.sanitize(label),
+		total:  total,
+		bytes:  bytes,
+		start:  now,
+		logged: now,
+		live:   l.live() && l.enabled(LevelInfo),
+	}
+}
+
+func (p *ProgressBar) begin() *ProgressBar {
+	if p.live {
+		p.logger.addLive(p)
+		return p
+	}
+	e := p.logger.Info()
+	if p.total > 0 {
+		e.Any("total", p.amount(p.total))
+	}
+	e.msg(p.label)
+	return p
+}
+
+func (p *ProgressBar) Add(n int64) {
+	p.mu.Lock()
+	p.current += n
+	p.mu.Unlock()
+	p.report()
+}
+
+func (p *ProgressBar) Set(n int64) {
+	p.mu.Lock()
+	p.current = n
+	p.mu.Unlock()
+	p.report()
+}
+
+func (p *ProgressBar) SetTotal(total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+}
+
+func (p *ProgressBar) Write(b []byte) (int, error) {
+	p.Add(int64(len(b)))
+	return len(b), nil
+}
+
+func (p *ProgressBar) Done() {
+	if e := p.finish(LevelSuccess); e != nil {
+		e.msg(p.label)
+	}
+}
+
+func (p *ProgressBar) Fail(err error) {
+	if e := p.finish(LevelError); e != nil {
+		e.Err(err).msg(p.label)
+	}
+}
+
+// report logs the current state on writers that cannot show the bar, at
+// most once per ProgressInterval. Terminals pick up changes on the next
+// redraw instead.
+func (p *ProgressBar) report() {
+	if p.live {
+		return
+	}
+	p.mu.Lock()
+	if p.done || time.Since(p.logged) < ProgressInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.logged = time.Now()
+	e := p.logger.Info()
+	p.addStats(e)
+	p.mu.Unlock()
+	e.msg(p.label)
+}
+
+func (p *ProgressBar) finish(level Level) *Entry {
+	p.mu.Lock()
+	if p.done {
+		p.mu.Unlock()
+		return nil
+	}
+	p.done = true
+	e := p.logger.newEntry(level)
+	e.Any("total", p.amount(p.current))
+	e.Elapsed = time.Since(p.start)
+	p.mu.Unlock()
+
+	if p.live {
+		p.logger.removeLive(p, nil)
+	}
+	return e
+}
+
+// addStats adds the progress, rate and ETA fields; p.mu must be held.
+func (p *ProgressBar) addStats(e *Entry) {
+	if p.total > 0 {
+		e.Any("progress", fmt.Sprintf("%d%%", p.percent()))
+		e.Any("done", p.amount(p.current)+"/"+p.amount(p.total))
+	} else {
+		e.Any("done", p.amount(p.current))
+	}
+	if rate := p.rate(); rate > 0 {
+		e.Any("rate", p.amount(int64(rate))+"/s")
+		if eta := p.eta(rate); eta > 0 {
+			e.Any("eta", eta)
+		}
+	}
+}
+
+// percent is the share of the total done, clamped as Add and Set may move
+// the count below zero or past the total; p.mu must be held.
+func (p *ProgressBar) percent() int64 {
+	return min(max(p.current, 0), p.total) * 100 / p.total
+}
+
+func (p *ProgressBar) rate() float64 {
+	elapsed := time.Since(p.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.current) / elapsed
+}
+
+func (p *ProgressBar) eta(rate float64) time.Duration {
+	if p.total <= 0 || rate <= 0 || p.current >= p.total {
+		return 0
+	}
+	return time.Duration(float64(p.total-p.current) / rate * float64(time.Second)).Round(time.Second)
+}
+
+func (p *ProgressBar) amount(n int64) string {
+	if p.bytes {
+		return humanBytes(n)
+	}
+	return fmt.Sprint(n)
+}
+
+func (p *ProgressBar) render(width int) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	label := p.label
+	if p.group != nil {
+		label += strings.Repeat(" ", max(p.group.labelWidth()-lipgloss.Width(label), 0))
+	}
+	info := p.amount(p.current)
+	if p.total > 0 {
+		info = fmt.Sprintf("%3d%%  %s/%s", p.percent(), info, p.amount(p.total))
+	}
+	if rate := p.rate(); rate > 0 {
+		info += "  " + p.amount(int64(rate)) + "/s"
+		if eta := p.eta(rate); eta > 0 {
+			info += "  ETA " + eta.String()
+		}
+	}
+
+	indent := strings.Repeat("  ", p.logger.indent)
+	barWidth := maxBarWidth
+	if width > 0 {
+		barWidth = width - lipgloss.Width(indent+label+info) - 6
+		barWidth = min(max(barWidth, minBarWidth), maxBarWidth)
+	}
+	pal := p.logger.palette()
+	return indent + pal.progress.Label.Render(label) + "  " + p.bar(pal, barWidth) + "  " + pal.progress.Info.Render(info)
+}
+
+func (p *ProgressBar) bar(pal *palette, width int) string {
+	if p.total <= 0 {
+		// a block bouncing back and forth
+		block := max(width/5, 1)
+		span := width - block
+		pos := int(time.Since(p.start)/liveInterval) % (2 * span)
+		if pos > span {
+			pos = 2*span - pos
+		}
+		return pal.progress.Empty.Render(strings.Repeat("░", pos)) +
+			pal.progress.Filled.Render(strings.Repeat("█", block)) +
+			pal.progress.Empty.Render(strings.Repeat("░", span-pos))
+	}
+	// Add and Set may move the count below zero or past the total
+	filled := int(min(max(p.current, 0), p.total) * int64(width) / p.total)
+	return pal.progress.Filled.Render(strings.Repeat("█", filled)) +
+		pal.progress.Empty.Render(strings.Repeat("░", width-filled))
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ProgressGroup is a set of progress bars drawn together with their labels
+// aligned.
+type ProgressGroup struct {
+	logger *Logger
+	mu     sync.Mutex
+	bars   []*ProgressBar
+}
+
+func NewProgressGroup() *ProgressGroup {
+	return logger.ProgressGroup()
+}
+
+func (l *Logger) ProgressGroup() *ProgressGroup {
+	return &ProgressGroup{logger: l}
+}
+
+func (g *ProgressGroup) Add(label string, total int64) *ProgressBar {
+	return g.add(label, total, false)
+}
+
+func (g *ProgressGroup) AddBytes(label string, total int64) *ProgressBar {
+	return g.add(label, total, true)
+}
+
+func (g *ProgressGroup) add(label string, total int64, bytes bool) *ProgressBar {
+	p := g.logger.newProgress(g, label, total, bytes)
+	g.mu.Lock()
+	g.bars = append(g.bars, p)
+	g.mu.Unlock()
+	return p.begin()
+}
+
+// Done finishes every bar of the group that is still running.
+func (g *ProgressGroup) Done() {
+	g.mu.Lock()
+	bars := append([]*ProgressBar(nil), g.bars...)
+	g.mu.Unlock()
+	for _, bar := range bars {
+		bar.Done()
+	}
+}
+
+func (g *ProgressGroup) labelWidth() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	width := 0
+	for _, bar := range g.bars {
+		width = max(width, lipgloss.Width(bar.label))
+	}
+	return width
+}
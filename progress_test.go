@@ -0,0 +1,97 @@
+package clog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressWithoutTerminal(t *testing.T) {
+	defer func(interval time.Duration) { ProgressInterval = interval }(ProgressInterval)
+	ProgressInterval = time.Hour
+
+	l, b := bufferLogger()
+	p := l.Progress("copy", 10)
+	p.Add(4)
+	p.Done()
+	p.Done()
+	l.ProgressBytes("upload", 0).Fail(errors.New("reset"))
+
+	got := elapsed.ReplaceAllString(uncolored(b.String()), "(t)")
+	want := `• copy
+  └─ total: 10
+✔ copy (t)
+  └─ total: 4
+• upload
+✖ upload (t)
+  ├─ total: 0 B
+  └─ err: reset
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProgressReportClamps(t *testing.T) {
+	defer func(interval time.Duration) { ProgressInterval = interval }(ProgressInterval)
+	ProgressInterval = 0
+
+	l, b := bufferLogger()
+	p := l.Progress("sync", 100)
+	for _, tt := range []struct {
+		set  int64
+		want string
+	}{
+		{50, "progress: 50%"},
+		{150, "progress: 100%"},
+		{-20, "progress: 0%"},
+	} {
+		b.Reset()
+		p.Set(tt.set)
+		if out := uncolored(b.String()); !strings.Contains(out, tt.want) {
+			t.Errorf("Set(%d): got %q, want %q", tt.set, out, tt.want)
+		}
+	}
+	p.SetTotal(200)
+	p.Set(300)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if got := p.bar(globalPalette(), 10); strings.Count(got, "█") != 10 {
+		t.Errorf("bar past the total: %q", got)
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:       "0 B",
+		1023:    "1023 B",
+		1024:    "1.0 KiB",
+		1536:    "1.5 KiB",
+		5 << 20: "5.0 MiB",
+		3 << 40: "3.0 TiB",
+	} {
+		if got := humanBytes(n); got != want {
+			t.Errorf("humanBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestProgressGroupLive(t *testing.T) {
+	term := newTestTerminal(t, 80)
+	l := New().SetWriter(term)
+	g := l.ProgressGroup()
+	a := g.Add("a", 4)
+	g.AddBytes("longer", 2048).Write(make([]byte, 1024))
+	a.Set(2)
+	line := uncolored(a.render(80))
+	g.Done()
+
+	if !strings.HasPrefix(line, "a       ") || !strings.Contains(line, " 50%  2/4") {
+		t.Errorf("bar line: %q", line)
+	}
+	out := uncolored(term.String())
+	if !strings.Contains(out, "✔ a") || !strings.Contains(out, "✔ longer") {
+		t.Errorf("bars not finished: %q", out)
+	}
+}
@@ -0,0 +1,54 @@
+package clog
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// protoWriter appends fields in the protobuf wire format, which is all the
+// exporters need to speak protobuf without generated code.
+type protoWriter struct {
+	b []byte
+}
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (w *protoWriter) tag(field, wire int) {
+	w.b = binary.AppendUvarint(w.b, uint64(field)<<3|uint64(wire))
+}
+
+func (w *protoWriter) varint(field int, v uint64) {
+	w.tag(field, wireVarint)
+	w.b = binary.AppendUvarint(w.b, v)
+}
+
+func (w *protoWriter) fixed64(field int, v uint64) {
+	w.tag(field, wireFixed64)
+	w.b = binary.LittleEndian.AppendUint64(w.b, v)
+}
+
+func (w *protoWriter) double(field int, v float64) {
+	w.fixed64(field, math.Float64bits(v))
+}
+
+func (w *protoWriter) bytes(field int, b []byte) {
+	w.tag(field, wireBytes)
+	w.b = binary.AppendUvarint(w.b, uint64(len(b)))
+	w.b = append(w.b, b...)
+}
+
+func (w *protoWriter) string(field int, s string) {
+	w.tag(field, wireBytes)
+	w.b = binary.AppendUvarint(w.b, uint64(len(s)))
+	w.b = append(w.b, s...)
+}
+
+func (w *protoWriter) message(field int, encode func(w *protoWriter)) {
+	var m protoWriter
+	encode(&m)
+	w.bytes(field, m.b)
+}
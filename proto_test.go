@@ -0,0 +1,104 @@
+package clog
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// protoFields is a decoded protobuf message: the values of each field in
+// order, as uint64 for varint and fixed64 fields and []byte otherwise.
+type protoFields map[int][]any
+
+func decodeProto(t *testing.T, b []byte) protoFields {
+	t.Helper()
+	m := protoFields{}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad tag in %x", b)
+		}
+		b = b[n:]
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("bad varint in field %d", field)
+			}
+			m[field] = append(m[field], v)
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				t.Fatalf("short fixed64 in field %d", field)
+			}
+			m[field] = append(m[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				t.Fatalf("bad length in field %d", field)
+			}
+			m[field] = append(m[field], b[n:n+int(size)])
+			b = b[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d in field %d", tag&7, field)
+		}
+	}
+	return m
+}
+
+func (m protoFields) uint(field int) uint64 {
+	if len(m[field]) == 0 {
+		return 0
+	}
+	return m[field][0].(uint64)
+}
+
+func (m protoFields) string(field int) string {
+	if len(m[field]) == 0 {
+		return ""
+	}
+	return string(m[field][0].([]byte))
+}
+
+func (m protoFields) messages(t *testing.T, field int) []protoFields {
+	t.Helper()
+	var msgs []protoFields
+	for _, v := range m[field] {
+		msgs = append(msgs, decodeProto(t, v.([]byte)))
+	}
+	return msgs
+}
+
+func (m protoFields) message(t *testing.T, field int) protoFields {
+	t.Helper()
+	msgs := m.messages(t, field)
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages in field %d, want 1", len(msgs), field)
+	}
+	return msgs[0]
+}
+
+func TestProtoWriter(t *testing.T) {
+	var w protoWriter
+	w.varint(1, 300)
+	w.string(2, "hello")
+	w.double(3, 1.5)
+	w.message(4, func(w *protoWriter) { w.varint(1, 1) })
+	w.bytes(20, nil)
+
+	// 300 is 0xac 0x02; field 20 takes a two byte tag
+	want := []byte{0x08, 0xac, 0x02, 0x12, 5, 'h', 'e', 'l', 'l', 'o', 0x19}
+	want = binary.LittleEndian.AppendUint64(want, math.Float64bits(1.5))
+	want = append(want, 0x22, 2, 0x08, 1, 0xa2, 0x01, 0)
+	if string(w.b) != string(want) {
+		t.Fatalf("got %x\nwant %x", w.b, want)
+	}
+
+	m := decodeProto(t, w.b)
+	if m.uint(1) != 300 || m.string(2) != "hello" || math.Float64frombits(m.uint(3)) != 1.5 ||
+		m.message(t, 4).uint(1) != 1 || len(m[20]) != 1 {
+		t.Errorf("decoded %v", m)
+	}
+}
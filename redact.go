@@ -0,0 +1,358 @@
+package clog
+
+import (
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const redacted = "****"
+
+// Secret wraps a sensitive string so it never reaches the output, whatever
+// formatter or sink the entry goes through.
+type Secret string
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return redacted
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+type Scrubber func(s string) string
+
+func RegexpScrubber(expr string) Scrubber {
+	re := regexp.MustCompile(expr)
+	return func(s string) string {
+		return re.ReplaceAllString(s, redacted)
+	}
+}
+
+var (
+	ScrubJWT       = RegexpScrubber(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	ScrubAWSKeys   = RegexpScrubber(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b`)
+	ScrubEmails    = RegexpScrubber(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	ScrubBearer    = RegexpScrubber(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`)
+	cardCandidates = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+func ScrubCardNumbers(s string) string {
+	return cardCandidates.ReplaceAllStringFunc(s, func(m string) string {
+		if !luhn(m) {
+			return m
+		}
+		return redacted
+	})
+}
+
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// DefaultRedactKeys are the exact names of fields that hold secrets. Globs
+// such as *token* are left to AddKeys, as they also match fields like
+// max_tokens.
+var DefaultRedactKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"client_secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"api_key",
+	"apikey",
+	"private_key",
+	"authorization",
+	"proxy-authorization",
+	"cookie",
+	"set-cookie",
+}
+
+// DefaultScrubbers find secrets and personal data in messages and string
+// values. They rewrite text that is often legitimate, such as email
+// addresses, so NewRedactor leaves them out; add them with AddScrubbers.
+var DefaultScrubbers = []Scrubber{ScrubBearer, ScrubJWT, ScrubAWSKeys, ScrubCardNumbers, ScrubEmails}
+
+type Redactor struct {
+	Keys      []string
+	Scrubbers []Scrubber
+	MaxDepth  int
+}
+
+// NewRedactor masks the fields named in DefaultRedactKeys, and leaves
+// messages as they are until scrubbers are added.
+func NewRedactor() *Redactor {
+	return &Redactor{
+		Keys:     append([]string(nil), DefaultRedactKeys...),
+		MaxDepth: 16,
+	}
+}
+
+func (r *Redactor) AddKeys(keys ...string) *Redactor {
+	r.Keys = append(r.Keys, keys...)
+	return r
+}
+
+func (r *Redactor) AddScrubbers(scrubbers ...Scrubber) *Redactor {
+	r.Scrubbers = append(r.Scrubbers, scrubbers...)
+	return r
+}
+
+func (r *Redactor) MatchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.Keys {
+		if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) Scrub(s string) string {
+	for _, scrub := range r.Scrubbers {
+		s = scrub(s)
+	}
+	return s
+}
+
+// Value returns v with deny-listed keys masked and every string it contains
+// passed through the scrubbers. Maps, slices and structs are copied rather
+// than modified, so the caller's data is left untouched.
+func (r *Redactor) Value(key string, v any) any {
+	if r.MatchKey(key) {
+		return Secret("")
+	}
+	switch v := v.(type) {
+	case nil, Secret:
+		return v
+	case string:
+		return r.Scrub(v)
+	case Object:
+		obj := make(Object, len(v))
+		for i, arg := range v {
+			obj[i] = Argument{Key: arg.Key, Value: r.Value(arg.Key, arg.Value)}
+		}
+		return obj
+	case Table:
+		rows := make([][]any, len(v.Rows))
+		for i, row := range v.Rows {
+			rows[i] = make([]any, len(row))
+			for j, cell := range row {
+				header := ""
+				if j < len(v.Headers) {
+					header = v.Headers[j]
+				}
+				rows[i][j] = r.Value(header, cell)
+			}
+		}
+		return Table{Headers: v.Headers, Rows: rows}
+	case error:
+		if msg := r.Scrub(v.Error()); msg != v.Error() {
+			return msg
+		}
+		return v
+	}
+	rv, changed := r.value(reflect.ValueOf(v), 0)
+	if !changed {
+		return v
+	}
+	return rv.Interface()
+}
+
+func (r *Redactor) value(rv reflect.Value, depth int) (reflect.Value, bool) {
+	if !rv.IsValid() || depth > r.MaxDepth || rv.Type() == secretType {
+		return rv, false
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		s := r.Scrub(rv.String())
+		if s == rv.String() {
+			return rv, false
+		}
+		nv := reflect.New(rv.Type()).Elem()
+		nv.SetString(s)
+		return nv, true
+
+	case reflect.Interface:
+		if rv.IsNil() {
+			return rv, false
+		}
+		// objects and tables are masked by their keys, not their fields
+		switch inner := rv.Elem().Interface().(type) {
+		case Object, Table:
+			nv := reflect.New(rv.Type()).Elem()
+			nv.Set(reflect.ValueOf(r.Value("", inner)))
+			return nv, true
+		}
+		inner, changed := r.value(rv.Elem(), depth+1)
+		if !changed {
+			return rv, false
+		}
+		nv := reflect.New(rv.Type()).Elem()
+		nv.Set(inner)
+		return nv, true
+
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return rv, false
+		}
+		inner, changed := r.value(rv.Elem(), depth+1)
+		if !changed {
+			return rv, false
+		}
+		nv := reflect.New(rv.Type().Elem())
+		nv.Elem().Set(inner)
+		return nv, true
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return rv, false
+		}
+		values, changed := map[any]reflect.Value{}, false
+		iter := rv.MapRange()
+		for iter.Next() {
+			k, v := iter.Key(), iter.Value()
+			if ks, ok := mapKeyString(k); ok && r.MatchKey(ks) {
+				values[k.Interface()], changed = r.mask(v.Type()), true
+				continue
+			}
+			if nv, ok := r.value(v, depth+1); ok {
+				values[k.Interface()], changed = nv, true
+			}
+		}
+		if !changed {
+			return rv, false
+		}
+		nv := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter = rv.MapRange()
+		for iter.Next() {
+			v := iter.Value()
+			if replaced, ok := values[iter.Key().Interface()]; ok {
+				v = replaced
+			}
+			nv.SetMapIndex(iter.Key(), v)
+		}
+		return nv, true
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && (rv.IsNil() || rv.Type().Elem().Kind() == reflect.Uint8) {
+			return rv, false
+		}
+		var nv reflect.Value
+		for i := 0; i < rv.Len(); i++ {
+			elem, changed := r.value(rv.Index(i), depth+1)
+			if !changed {
+				continue
+			}
+			if !nv.IsValid() {
+				nv = copyList(rv)
+			}
+			nv.Index(i).Set(elem)
+		}
+		return nv, nv.IsValid()
+
+	case reflect.Struct:
+		var nv reflect.Value
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			elem, changed := r.mask(field.Type), true
+			if name := fieldName(field); !r.MatchKey(name) && !r.MatchKey(field.Name) {
+				if elem, changed = r.value(rv.Field(i), depth+1); !changed {
+					continue
+				}
+			}
+			if !nv.IsValid() {
+				nv = reflect.New(t).Elem()
+				nv.Set(rv)
+			}
+			nv.Field(i).Set(elem)
+		}
+		return nv, nv.IsValid()
+	}
+
+	return rv, false
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+func (r *Redactor) mask(t reflect.Type) reflect.Value {
+	nv := reflect.New(t).Elem()
+	switch {
+	case t.Kind() == reflect.String:
+		nv.SetString(redacted)
+	case secretType.AssignableTo(t):
+		nv.Set(reflect.ValueOf(Secret("")))
+	}
+	return nv
+}
+
+func copyList(rv reflect.Value) reflect.Value {
+	if rv.Kind() == reflect.Array {
+		nv := reflect.New(rv.Type()).Elem()
+		nv.Set(rv)
+		return nv
+	}
+	nv := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+	reflect.Copy(nv, rv)
+	return nv
+}
+
+func mapKeyString(k reflect.Value) (string, bool) {
+	if k.Kind() == reflect.Interface {
+		k = k.Elem()
+	}
+	if k.Kind() != reflect.String {
+		return "", false
+	}
+	return k.String(), true
+}
+
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func (l *Logger) redact(msg string, e *Entry) string {
+	if l.Redactor == nil {
+		return msg
+	}
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		e.Fields.Set(it.Key, l.Redactor.Value(it.Key, it.Value))
+	}
+	return l.Redactor.Scrub(msg)
+}
@@ -0,0 +1,167 @@
+package clog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotateTimeFormat = "20060102T150405.000"
+
+// RotatingFile is a log file that is renamed to a timestamped backup, such
+// as app-20240102T150405.000.log, once it grows past MaxSize bytes. A
+// backup made in the same millisecond as another gets a number, as in
+// app-20240102T150405.000-1.log. Backups beyond MaxBackups, or older than
+// MaxAge, are removed; zero keeps them.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+	MaxAge     time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate starts a new file, even if the current one is below MaxSize.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	if err := os.Rename(f.Path, f.backupName(time.Now())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f.prune()
+	return f.open()
+}
+
+// backupName returns a name for a backup made at t that no file has yet, as
+// renaming onto an existing backup would replace it. Numbers count up past
+// the backups already made in the same millisecond, so prune keeps order.
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.Path)
+	name := strings.TrimSuffix(f.Path, ext) + "-" + t.Format(rotateTimeFormat)
+	matches, _ := filepath.Glob(name + "*" + ext)
+	if len(matches) == 0 {
+		return name + ext
+	}
+	last := 0
+	for _, match := range matches {
+		seq := strings.TrimPrefix(match[len(name):len(match)-len(ext)], "-")
+		if n, err := strconv.Atoi(seq); err == nil && n > last {
+			last = n
+		}
+	}
+	return fmt.Sprintf("%s-%d%s", name, last+1, ext)
+}
+
+// prune removes the backups that MaxBackups and MaxAge do not keep. Errors
+// are ignored, as a backup left behind does no harm to logging.
+func (f *RotatingFile) prune() {
+	if f.MaxBackups <= 0 && f.MaxAge <= 0 {
+		return
+	}
+	ext := filepath.Ext(f.Path)
+	prefix := filepath.Base(strings.TrimSuffix(f.Path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(f.Path))
+	if err != nil {
+		return
+	}
+	type backup struct {
+		path string
+		time time.Time
+		seq  int
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp, seq, numbered := strings.Cut(name[len(prefix):len(name)-len(ext)], "-")
+		t, err := time.ParseInLocation(rotateTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		n := 0
+		if numbered {
+			if n, err = strconv.Atoi(seq); err != nil {
+				continue
+			}
+		}
+		backups = append(backups, backup{filepath.Join(filepath.Dir(f.Path), name), t, n})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.After(backups[j].time)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+	for i, b := range backups {
+		if (f.MaxBackups > 0 && i >= f.MaxBackups) || (f.MaxAge > 0 && time.Since(b.time) > f.MaxAge) {
+			_ = os.Remove(b.path)
+		}
+	}
+}
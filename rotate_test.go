@@ -0,0 +1,97 @@
+package clog
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backupContents returns the contents of the backups of f, newest first.
+func backupContents(t *testing.T, f *RotatingFile) []string {
+	t.Helper()
+	names, err := filepath.Glob(strings.TrimSuffix(f.Path, ".log") + "-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, string(data))
+	}
+	// the lines count up, so the newest backup sorts last
+	slices.Sort(contents)
+	slices.Reverse(contents)
+	return contents
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	f := &RotatingFile{Path: filepath.Join(t.TempDir(), "app.log"), MaxSize: 10}
+	defer f.Close()
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(f.Path)
+	if err != nil || string(data) != "line 3\n" {
+		t.Errorf("current file %q, %v", data, err)
+	}
+	// the rotations happen within a millisecond, and keep both backups
+	if got, want := backupContents(t, f), []string{"line 2\n", "line 1\n"}; !slices.Equal(got, want) {
+		t.Errorf("backups %q, want %q", got, want)
+	}
+}
+
+func TestRotatingFileMaxBackups(t *testing.T) {
+	f := &RotatingFile{Path: filepath.Join(t.TempDir(), "app.log"), MaxBackups: 2}
+	defer f.Close()
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := backupContents(t, f), []string{"line 4\n", "line 3\n"}; !slices.Equal(got, want) {
+		t.Errorf("backups %q, want %q", got, want)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	f := &RotatingFile{Path: filepath.Join(dir, "app.log"), MaxAge: 24 * time.Hour}
+	defer f.Close()
+	now := time.Now()
+	old := "app-" + now.Add(-48*time.Hour).Format(rotateTimeFormat) + ".log"
+	recent := "app-" + now.Add(-time.Hour).Format(rotateTimeFormat) + "-1.log"
+	for _, name := range []string{old, recent, "app-notes.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := f.Write([]byte("line\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if slices.Contains(names, old) || !slices.Contains(names, recent) ||
+		!slices.Contains(names, "app-notes.log") || len(names) != 4 {
+		t.Errorf("got files %q", names)
+	}
+}
@@ -0,0 +1,375 @@
+package clog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShipSink streams entries as newline delimited JSON to a collector over
+// TCP, or TLS with TLSConfig set. While the collector cannot be reached,
+// entries are spooled to a file in SpoolDir, up to MaxSpoolBytes, and
+// replayed in order once it can. A spool left by a previous run is replayed
+// first. Logger.Receive is the collector's side.
+type ShipSink struct {
+	Address       string
+	TLSConfig     *tls.Config
+	SpoolDir      string
+	MaxSpoolBytes int64
+	QueueSize     int
+	DialTimeout   time.Duration
+
+	once      sync.Once
+	mu        sync.Mutex
+	closed    bool
+	queue     chan []byte
+	done      chan struct{}
+	formatter JSONFormatter
+
+	conn       net.Conn
+	broken     *atomic.Bool
+	spool      *os.File
+	spoolBytes int64
+	nextDial   time.Time
+	backoff    time.Duration
+
+	sent     atomic.Uint64
+	spooled  atomic.Uint64
+	replayed atomic.Uint64
+	dropped  atomic.Uint64
+	up       atomic.Bool
+}
+
+// ShipStats counts entries by what happened to them.
+type ShipStats struct {
+	Sent       uint64
+	Spooled    uint64
+	Replayed   uint64
+	Dropped    uint64
+	SpoolBytes int64
+	Connected  bool
+}
+
+const (
+	minShipBackoff = time.Second
+	maxShipBackoff = 30 * time.Second
+	shipSpoolFile  = "clog-spool.ndjson"
+)
+
+func NewShipSink(address, spoolDir string) *ShipSink {
+	return &ShipSink{
+		Address:       address,
+		SpoolDir:      spoolDir,
+		MaxSpoolBytes: 64 << 20,
+		QueueSize:     4096,
+		DialTimeout:   5 * time.Second,
+	}
+}
+
+func (s *ShipSink) WriteEntry(e *Entry) error {
+	s.once.Do(s.start)
+	line := s.formatter.Format(e)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.dropped.Add(1)
+		return nil
+	}
+	select {
+	case s.queue <- line:
+	default:
+		s.dropped.Add(1)
+	}
+	return nil
+}
+
+func (s *ShipSink) Stats() ShipStats {
+	s.mu.Lock()
+	spoolBytes := s.spoolBytes
+	s.mu.Unlock()
+	return ShipStats{
+		Sent:       s.sent.Load(),
+		Spooled:    s.spooled.Load(),
+		Replayed:   s.replayed.Load(),
+		Dropped:    s.dropped.Load(),
+		SpoolBytes: spoolBytes,
+		Connected:  s.up.Load(),
+	}
+}
+
+// Close sends or spools the queued entries and closes the connection.
+func (s *ShipSink) Close() error {
+	s.once.Do(s.start)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+func (s *ShipSink) start() {
+	s.queue = make(chan []byte, max(s.QueueSize, 1))
+	s.done = make(chan struct{})
+	if s.SpoolDir != "" {
+		if err := os.MkdirAll(s.SpoolDir, 0o755); err == nil {
+			s.spool, _ = os.OpenFile(filepath.Join(s.SpoolDir, shipSpoolFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+		}
+		if s.spool != nil {
+			if info, err := s.spool.Stat(); err == nil {
+				s.setSpoolBytes(info.Size())
+			}
+		}
+	}
+	go s.run()
+}
+
+func (s *ShipSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(minShipBackoff)
+	defer ticker.Stop()
+	for {
+		select {
+		case line, ok := <-s.queue:
+			if !ok {
+				s.disconnect()
+				if s.spool != nil {
+					_ = s.spool.Close()
+				}
+				return
+			}
+			s.ship(line)
+		case <-ticker.C:
+			s.connect()
+		}
+	}
+}
+
+// ship sends line once everything spooled before it is sent, and spools it
+// otherwise.
+func (s *ShipSink) ship(line []byte) {
+	if s.connect() {
+		if err := s.send(line); err == nil {
+			s.sent.Add(1)
+			return
+		}
+		s.disconnect()
+	}
+	s.spoolLine(line)
+}
+
+// connect makes sure there is a connection with nothing left to replay,
+// dialing at most once per backoff period.
+func (s *ShipSink) connect() bool {
+	if s.conn != nil && s.broken.Load() {
+		s.disconnect()
+	}
+	if s.conn == nil {
+		if time.Now().Before(s.nextDial) {
+			return false
+		}
+		conn, err := s.dial()
+		if err != nil {
+			s.backoff = min(max(s.backoff*2, minShipBackoff), maxShipBackoff)
+			s.nextDial = time.Now().Add(s.backoff)
+			return false
+		}
+		broken := &atomic.Bool{}
+		s.conn, s.broken, s.backoff = conn, broken, 0
+		s.up.Store(true)
+		// the collector never writes, so a read returns when it hangs up
+		go func() {
+			_, _ = io.Copy(io.Discard, conn)
+			broken.Store(true)
+		}()
+	}
+	if err := s.replay(); err != nil {
+		s.disconnect()
+		return false
+	}
+	return true
+}
+
+func (s *ShipSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.DialTimeout}
+	if s.TLSConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", s.Address, s.TLSConfig)
+	}
+	return dialer.Dial("tcp", s.Address)
+}
+
+func (s *ShipSink) disconnect() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	s.up.Store(false)
+}
+
+func (s *ShipSink) send(line []byte) error {
+	if s.broken.Load() {
+		return net.ErrClosed
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := s.conn.Write(line)
+	return err
+}
+
+func (s *ShipSink) spoolLine(line []byte) {
+	if s.spool == nil || s.spoolBytes+int64(len(line)) > s.MaxSpoolBytes {
+		s.dropped.Add(1)
+		return
+	}
+	n, err := s.spool.Write(line)
+	s.setSpoolBytes(s.spoolBytes + int64(n))
+	if err != nil {
+		s.dropped.Add(1)
+		return
+	}
+	s.spooled.Add(1)
+}
+
+// replay sends the spooled entries in order. On failure, the entries not
+// sent yet are kept for the next attempt.
+func (s *ShipSink) replay() error {
+	if s.spool == nil || s.spoolBytes == 0 {
+		return nil
+	}
+	if _, err := s.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(s.spool)
+	var sendErr error
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 && bytes.HasSuffix(line, []byte("\n")) {
+			if sendErr = s.send(line); sendErr != nil {
+				rest, _ := io.ReadAll(r)
+				return errors.Join(sendErr, s.rewriteSpool(append(line, rest...)))
+			}
+			s.replayed.Add(1)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return s.rewriteSpool(nil)
+}
+
+// rewriteSpool replaces the spool with rest. The new spool is written to a
+// temporary file renamed over the old one, so a crash midway leaves either
+// spool rather than a truncated one.
+func (s *ShipSink) rewriteSpool(rest []byte) error {
+	path := filepath.Join(s.SpoolDir, shipSpoolFile)
+	tmp, err := os.CreateTemp(s.SpoolDir, shipSpoolFile+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(rest)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// the spool is closed first, as Windows cannot rename over an open file
+	_ = s.spool.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	spool, openErr := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if openErr != nil {
+		s.spool = nil
+		s.setSpoolBytes(0)
+		return errors.Join(err, openErr)
+	}
+	s.spool = spool
+	if info, statErr := spool.Stat(); statErr == nil {
+		s.setSpoolBytes(info.Size())
+	}
+	return err
+}
+
+func (s *ShipSink) setSpoolBytes(n int64) {
+	s.mu.Lock()
+	s.spoolBytes = n
+	s.mu.Unlock()
+}
+
+func Receive(ln net.Listener) error {
+	return logger.Receive(ln)
+}
+
+// Receive accepts connections from ShipSinks on ln and logs the entries
+// they send through l, until ln is closed.
+func (l *Logger) Receive(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go l.receive(conn)
+	}
+}
+
+// maxReceiveLine bounds the entries Receive accepts; longer lines are
+// dropped.
+const maxReceiveLine = 1 << 20
+
+func (l *Logger) receive(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxReceiveLine)
+	scanner.Split(scanLinesUpTo(maxReceiveLine))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) > 0 {
+			if e, err := l.ParseJSON(line); err == nil {
+				l.print(e)
+			}
+		}
+	}
+}
+
+// scanLinesUpTo splits lines like bufio.ScanLines, but skips the lines
+// longer than limit instead of failing, so one oversized entry does not end
+// the connection.
+func scanLinesUpTo(limit int) bufio.SplitFunc {
+	skipping := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			if skipping {
+				skipping = false
+				return i + 1, nil, nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if len(data) >= limit || atEOF && skipping {
+			skipping = true
+			return len(data), nil, nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
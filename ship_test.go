@@ -0,0 +1,195 @@
+package clog
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readMessages returns the msg of the first n lines received by ln.
+func readMessages(t *testing.T, ln net.Listener, n int) <-chan []string {
+	t.Helper()
+	out := make(chan []string, 1)
+	go func() {
+		var msgs []string
+		defer func() { out <- msgs }()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		r := bufio.NewReader(conn)
+		for len(msgs) < n {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			var entry struct{ Msg string }
+			if json.Unmarshal(line, &entry) == nil {
+				msgs = append(msgs, entry.Msg)
+			}
+		}
+	}()
+	return out
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShipSpoolsAndReplaysInOrder(t *testing.T) {
+	// take a free port, with nothing listening on it yet
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s := NewShipSink(addr, t.TempDir())
+	for _, msg := range []string{"one", "two", "three"} {
+		s.WriteEntry(testEntry(LevelInfo, msg))
+	}
+	waitFor(t, "entries to be spooled", func() bool { return s.Stats().Spooled == 3 })
+	if stats := s.Stats(); stats.Connected || stats.SpoolBytes == 0 {
+		t.Fatalf("stats %+v, want a spool and no connection", stats)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	received := readMessages(t, ln, 5)
+	s.WriteEntry(testEntry(LevelInfo, "four"))
+	waitFor(t, "the spool to be replayed", func() bool { return s.Stats().Replayed >= 3 })
+	s.WriteEntry(testEntry(LevelInfo, "five"))
+	s.Close()
+
+	if got, want := <-received, []string{"one", "two", "three", "four", "five"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("received %q, want %q", got, want)
+	}
+	if stats := s.Stats(); stats.SpoolBytes != 0 || stats.Dropped != 0 {
+		t.Errorf("stats %+v, want an empty spool and nothing dropped", stats)
+	}
+}
+
+func TestShipReplaysPreviousSpool(t *testing.T) {
+	dir := t.TempDir()
+	var spool []byte
+	for _, msg := range []string{"old one", "old two"} {
+		spool = append(spool, (&JSONFormatter{}).Format(testEntry(LevelInfo, msg))...)
+	}
+	// a line cut short by a crash is left out
+	spool = append(spool, `{"msg":"cut`...)
+	if err := os.WriteFile(filepath.Join(dir, shipSpoolFile), spool, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := readMessages(t, ln, 3)
+
+	s := NewShipSink(ln.Addr().String(), dir)
+	s.WriteEntry(testEntry(LevelInfo, "new"))
+	s.Close()
+
+	if got, want := <-received, []string{"old one", "old two", "new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("received %q, want %q", got, want)
+	}
+	if stats := s.Stats(); stats.Replayed != 2 || stats.Sent != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestShipSpoolLimit(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s := NewShipSink(addr, t.TempDir())
+	line := (&JSONFormatter{}).Format(testEntry(LevelInfo, "x"))
+	s.MaxSpoolBytes = int64(len(line)) * 2
+	for i := 0; i < 3; i++ {
+		s.WriteEntry(testEntry(LevelInfo, "x"))
+	}
+	s.Close()
+	if stats := s.Stats(); stats.Spooled != 2 || stats.Dropped != 1 {
+		t.Errorf("stats %+v, want 2 spooled and 1 dropped", stats)
+	}
+}
+
+func TestShipRewriteSpool(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, shipSpoolFile)
+	if err := os.WriteFile(path, []byte("sent\nkept\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewShipSink("", dir)
+	spool, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.spool = spool
+	defer func() { s.spool.Close() }()
+
+	if err := s.rewriteSpool([]byte("kept\n")); err != nil {
+		t.Fatal(err)
+	}
+	// later entries go after what was kept
+	s.spoolLine([]byte("new\n"))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "kept\nnew\n" || s.Stats().SpoolBytes != int64(len(got)) {
+		t.Errorf("spool %q, %d bytes", got, s.Stats().SpoolBytes)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("got %d files in the spool directory, want the spool alone", len(files))
+	}
+}
+
+func TestScanLinesUpTo(t *testing.T) {
+	long := strings.Repeat("x", 40)
+	for _, tt := range []struct {
+		input string
+		want  []string
+	}{
+		{"one\ntwo\n", []string{"one", "two"}},
+		{"one\n" + long + "\ntwo", []string{"one", "two"}},
+		{"one\n" + long, []string{"one"}},
+		{long + long + "\n\ntwo\n", []string{"", "two"}},
+	} {
+		scanner := bufio.NewScanner(strings.NewReader(tt.input))
+		scanner.Buffer(make([]byte, 0, 4), 16)
+		scanner.Split(scanLinesUpTo(16))
+		var got []string
+		for scanner.Scan() {
+			got = append(got, scanner.Text())
+		}
+		if scanner.Err() != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, %v, want %q", tt.input, got, scanner.Err(), tt.want)
+		}
+	}
+}
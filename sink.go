@@ -0,0 +1,87 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Sink receives every entry that passes the logger's level, after values
+// are resolved and redacted, in addition to the logger's Writer. Sinks are
+// called with the logger's lock held, so slow destinations should buffer
+// and ship entries in the background.
+type Sink interface {
+	WriteEntry(e *Entry) error
+	Close() error
+}
+
+func AddSink(sink Sink) *Logger {
+	return logger.AddSink(sink)
+}
+
+func (l *Logger) AddSink(sink Sink) *Logger {
+	l.Sinks = append(l.Sinks[:len(l.Sinks):len(l.Sinks)], sink)
+	return l
+}
+
+// writeSinks passes e to the sinks; the lock must be held. Like write errors on
+// the Writer, sink errors do not stop logging. Summary entries repeat what
+// the sinks already received, so they only go to the Writer.
+func (l *Logger) writeSinks(e *Entry) {
+	if e.Kind == KindSummary {
+		return
+	}
+	for _, sink := range l.Sinks {
+		_ = sink.WriteEntry(e)
+	}
+}
+
+func (l *Logger) closeSinks() error {
+	var errs []error
+	for _, sink := range l.Sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriterSink writes entries to Writer in the format of Formatter, such as
+// JSON to a file next to the logger's pretty output. Close closes Writer
+// when it is an io.Closer.
+type WriterSink struct {
+	Writer    io.Writer
+	Formatter Formatter
+}
+
+func (s *WriterSink) WriteEntry(e *Entry) error {
+	_, err := s.Writer.Write(s.Formatter.Format(e))
+	return err
+}
+
+func (s *WriterSink) Close() error {
+	if closer, ok := s.Writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// sinkFields returns the caller, event fields and fields of e as flat
+// string pairs, for destinations without nested values.
+func sinkFields(e *Entry) []Argument {
+	l := e.Logger
+	var fields []Argument
+	add := func(key, value string) {
+		fields = append(fields, Argument{Key: key, Value: value})
+	}
+	if e.Caller != "" {
+		add("caller", e.Caller)
+	}
+	for _, arg := range eventFields(e) {
+		add(arg.Key, fmt.Sprint(arg.Value))
+	}
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		l.flatten(l.sanitize(it.Key), it.Value, add)
+	}
+	return fields
+}
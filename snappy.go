@@ -0,0 +1,74 @@
+package clog
+
+import "encoding/binary"
+
+// snappyEncode compresses src in the snappy block format, as Loki expects
+// for protobuf pushes: the uncompressed length followed by literals and
+// copies found through a hash table of four byte sequences.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		// copies reach back at most 64KiB, so blocks are compressed apart
+		n := min(len(src), 1<<16)
+		dst = snappyBlock(dst, src[:n])
+		src = src[n:]
+	}
+	return dst
+}
+
+func snappyBlock(dst, src []byte) []byte {
+	const tableBits = 14
+	var table [1 << tableBits]int32
+	load := func(i int) uint32 {
+		return binary.LittleEndian.Uint32(src[i:])
+	}
+
+	lit := 0
+	for i := 0; i+4 <= len(src); {
+		h := load(i) * 0x1e35a7bd >> (32 - tableBits)
+		// positions are stored plus one, zero marks an empty slot
+		candidate := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if candidate < 0 || load(candidate) != load(i) {
+			i++
+			continue
+		}
+		dst = snappyLiteral(dst, src[lit:i])
+		n := 4
+		for i+n < len(src) && src[candidate+n] == src[i+n] {
+			n++
+		}
+		dst = snappyCopy(dst, i-candidate, n)
+		i += n
+		lit = i
+	}
+	return snappyLiteral(dst, src[lit:])
+}
+
+func snappyLiteral(dst, lit []byte) []byte {
+	if len(lit) == 0 {
+		return dst
+	}
+	n := len(lit) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2)
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	default:
+		dst = append(dst, 62<<2, byte(n), byte(n>>8), byte(n>>16))
+	}
+	return append(dst, lit...)
+}
+
+// snappyCopy emits copies with a two byte offset, of up to 64 bytes each.
+func snappyCopy(dst []byte, offset, length int) []byte {
+	for length > 0 {
+		n := min(length, 64)
+		dst = append(dst, byte(n-1)<<2|2, byte(offset), byte(offset>>8))
+		length -= n
+	}
+	return dst
+}
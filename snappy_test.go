@@ -0,0 +1,95 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// snappyDecode decodes the snappy block format, checking every length and
+// offset, to verify what snappyEncode produces.
+func snappyDecode(src []byte) ([]byte, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, errors.New("bad length")
+	}
+	src = src[n:]
+	dst := make([]byte, 0, size)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0:
+			length := int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, errors.New("short literal length")
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			length++
+			if len(src) < length {
+				return nil, errors.New("short literal")
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+		case 2:
+			if len(src) < 3 {
+				return nil, errors.New("short copy")
+			}
+			length := int(tag>>2) + 1
+			offset := int(src[1]) | int(src[2])<<8
+			src = src[3:]
+			if offset == 0 || offset > len(dst) {
+				return nil, errors.New("bad offset")
+			}
+			for i := 0; i < length; i++ {
+				dst = append(dst, dst[len(dst)-offset])
+			}
+		default:
+			return nil, errors.New("unexpected tag")
+		}
+	}
+	if uint64(len(dst)) != size {
+		return nil, errors.New("length mismatch")
+	}
+	return dst, nil
+}
+
+func TestSnappyEncode(t *testing.T) {
+	random := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(random)
+	lines := strings.Repeat(`level=info msg="request served" status=200 path=/api/v1/items`+"\n", 3000)
+
+	for name, src := range map[string][]byte{
+		"empty":    nil,
+		"short":    []byte("abc"),
+		"run":      bytes.Repeat([]byte{'a'}, 1000),
+		"overlap":  []byte("abcabcabcabcabcabcabcabcabcabc"),
+		"random":   random,
+		"lines":    []byte(lines),
+		"literals": append(random[:300:300], random[:300]...),
+		"blocks":   bytes.Repeat([]byte("0123456789abcdef"), 10000),
+	} {
+		encoded := snappyEncode(src)
+		decoded, err := snappyDecode(encoded)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(decoded, src) {
+			t.Errorf("%s: round trip changed the input", name)
+		}
+		if name == "lines" && len(encoded) > len(src)/10 {
+			t.Errorf("%s: %d bytes compressed to %d", name, len(src), len(encoded))
+		}
+	}
+}
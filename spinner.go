@@ -0,0 +1,113 @@
+package clog
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	SpinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	SpinnerStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("51"))
+)
+
+// Spinner animates a status line in place while a long operation runs. On
+// writers that are not terminals it logs the start and the final status
+// only.
+type Spinner struct {
+	logger *Logger
+	mu     sync.Mutex
+	msg    string
+	start  time.Time
+	live   bool
+	done   bool
+}
+
+func StartSpinner(msg string) *Spinner {
+	return logger.Spinner(msg)
+}
+
+func (l *Logger) Spinner(msg string) *Spinner {
+	s := &Spinner{
+		logger: l,
+		msg:    l.sanitize(msg),
+		start:  time.Now(),
+		live:   l.live() && l.enabled(LevelInfo),
+	}
+	if s.live {
+		l.addLive(s)
+	} else {
+		l.Info().msg(msg)
+	}
+	return s
+}
+
+func (s *Spinner) Update(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msg = s.logger.sprintf(format, args...)
+}
+
+// Success ends the spinner with a success line, using the spinner's own
+// message when format is empty.
+func (s *Spinner) Success(format string, args ...any) {
+	if e := s.finish(LevelSuccess); e != nil {
+		// the spinner's message was escaped when it was set
+		e.escaped = true
+		if format == "" {
+			e.msg(s.message())
+			return
+		}
+		e.msg(s.logger.sprintf(format, args...))
+	}
+}
+
+func (s *Spinner) Error(err error) {
+	if e := s.finish(LevelError); e != nil {
+		e.escaped = true
+		e.Err(err).msg(s.message())
+	}
+}
+
+// Stop removes the spinner without logging a final status.
+func (s *Spinner) Stop() {
+	s.finish(LevelInfo)
+}
+
+func (s *Spinner) message() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.msg
+}
+
+// finish takes the spinner off the screen and returns the entry for its
+// final status, or nil when it was already finished. The spinner lock is
+// released first since redrawing the region takes the logger lock.
+func (s *Spinner) finish(level Level) *Entry {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return nil
+	}
+	s.done = true
+	s.mu.Unlock()
+
+	if s.live {
+		s.logger.removeLive(s, nil)
+	}
+	e := s.logger.newEntry(level)
+	e.Elapsed = time.Since(s.start)
+	return e
+}
+
+func (s *Spinner) render(width int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elapsed := time.Since(s.start)
+	frame := SpinnerFrames[int(elapsed/liveInterval)%len(SpinnerFrames)]
+	return strings.Repeat("  ", s.logger.indent) +
+		SpinnerStyle.Render(frame) + " " + s.msg + " " +
+		lipgloss.NewStyle().Foreground(s.logger.palette().muted).Render(formatElapsed(elapsed.Truncate(time.Second)))
+}
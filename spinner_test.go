@@ -0,0 +1,67 @@
+package clog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSpinnerWithoutTerminal(t *testing.T) {
+	l, b := bufferLogger()
+	s := l.Spinner("fetching\x1b[2J")
+	s.Update("fetched %d of %d", 1, 2)
+	s.Success("")
+	l.Spinner("again").Error(errors.New("offline"))
+
+	got := elapsed.ReplaceAllString(uncolored(b.String()), "(t)")
+	want := `• fetching\x1b[2J
+✔ fetched 1 of 2 (t)
+• again
+✖ again (t)
+  └─ err: offline
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSpinnerLive(t *testing.T) {
+	term := newTestTerminal(t, 80)
+	l := New().SetWriter(term)
+	s := l.Spinner("working")
+	l.Info().Msg("meanwhile")
+	s.Success("finished")
+	s.Success("again")
+
+	out := term.String()
+	if strings.Count(out, "working") < 2 {
+		t.Errorf("spinner not redrawn below the log line: %q", out)
+	}
+	if !strings.Contains(out, "\x1b[1A\r\x1b[J") {
+		t.Errorf("spinner line never erased: %q", out)
+	}
+	tail := out[strings.LastIndex(out, "\x1b[J")+len("\x1b[J"):]
+	if strings.Contains(tail, "working") || !strings.Contains(uncolored(tail), "✔ finished") {
+		t.Errorf("spinner left on screen: %q", tail)
+	}
+	if len(l.regionState().items) != 0 || l.regionState().stop != nil {
+		t.Error("live region not cleared")
+	}
+	if strings.Contains(out, "again") {
+		t.Error("finished twice")
+	}
+}
+
+type sliceWriter struct{ lines []string }
+
+func (w sliceWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestSameWriter(t *testing.T) {
+	var b strings.Builder
+	if !sameWriter(&b, &b) || sameWriter(&b, &strings.Builder{}) || sameWriter(&b, nil) || !sameWriter(nil, nil) {
+		t.Error("pointer writers compared wrongly")
+	}
+	if sameWriter(sliceWriter{}, sliceWriter{}) {
+		t.Error("uncomparable writers reported as the same")
+	}
+}
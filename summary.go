@@ -0,0 +1,198 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const KindSummary = "summary"
+
+// maxCollected caps the distinct messages kept for the summary. Repeats of a
+// kept message are still counted.
+const maxCollected = 100
+
+// summary counts the entries of a logger and its clones by level and, when
+// collecting, keeps the warnings and errors to repeat at the end of a run.
+// It is guarded by the loggers' common lock.
+type summary struct {
+	counts  [LevelFatal + 1]int
+	items   []*summaryItem
+	index   map[summaryKey]*summaryItem
+	dropped int
+}
+
+type summaryKey struct {
+	level   Level
+	task    string
+	message string
+}
+
+type summaryItem struct {
+	summaryKey
+	count int
+}
+
+func collected(level Level) bool {
+	return level == LevelWarn || level >= LevelError
+}
+
+// add records an entry; the lock must be held.
+func (s *summary) add(e *Entry, collect bool) {
+	s.counts[e.Level]++
+	if !collect || !collected(e.Level) {
+		return
+	}
+	key := summaryKey{level: e.Level, message: e.Message}
+	if e.Task != nil {
+		key.task = e.Task.Name()
+	}
+	if item, ok := s.index[key]; ok {
+		item.count++
+		return
+	}
+	if len(s.items) >= maxCollected {
+		s.dropped++
+		return
+	}
+	if s.index == nil {
+		s.index = make(map[summaryKey]*summaryItem)
+	}
+	item := &summaryItem{summaryKey: key, count: 1}
+	s.items = append(s.items, item)
+	s.index[key] = item
+}
+
+func Count(levels ...Level) int {
+	return logger.Count(levels...)
+}
+
+func Summary() {
+	logger.Summary()
+}
+
+func Close() error {
+	return logger.Close()
+}
+
+// WithCollector keeps warnings and errors so Summary can repeat them.
+func (l *Logger) WithCollector(with bool) *Logger {
+	l.Collect = with
+	return l
+}
+
+// WithExitSummary logs the summary on Close and before a Fatal entry exits.
+// It turns on the collector.
+func (l *Logger) WithExitSummary(with bool) *Logger {
+	l.ExitSummary = with
+	if with {
+		l.Collect = true
+	}
+	return l
+}
+
+// Count returns how many entries were logged at the given levels by the
+// logger and the task loggers derived from it, e.g. to pick an exit code:
+//
+//	if log.Count(clog.LevelError, clog.LevelFatal) > 0 {
+//		os.Exit(1)
+//	}
+func (l *Logger) Count(levels ...Level) int {
+	l.lock()
+	defer l.unlock()
+	n := 0
+	for _, level := range levels {
+		if level >= 0 && level <= LevelFatal {
+			n += l.summaryState().counts[level]
+		}
+	}
+	return n
+}
+
+// Summary logs the number of warnings and errors so far, followed by the
+// collected messages grouped by level, each once with its number of
+// repeats.
+func (l *Logger) Summary() {
+	l.lock()
+	s := l.summaryState()
+	warnings := s.counts[LevelWarn]
+	errors := s.counts[LevelError] + s.counts[LevelFatal]
+	var items []summaryItem
+	for _, level := range []Level{LevelFatal, LevelError, LevelWarn} {
+		for _, item := range s.items {
+			if item.level == level {
+				items = append(items, *item)
+			}
+		}
+	}
+	dropped := s.dropped
+	l.unlock()
+
+	level := LevelSuccess
+	switch {
+	case errors > 0:
+		level = LevelError
+	case warnings > 0:
+		level = LevelWarn
+	}
+	e := l.newEntry(level)
+	e.Kind = KindSummary
+	e.Any("warnings", warnings).Any("errors", errors)
+	e.msg(summaryText(warnings, errors))
+
+	nested := l.clone()
+	nested.indent++
+	for _, item := range items {
+		e := nested.newEntry(item.level)
+		e.Kind = KindSummary
+		if item.task != "" {
+			e.Any("task", item.task)
+		}
+		if item.count > 1 {
+			e.Any("count", item.count)
+		}
+		// a Fatal entry is only repeated here, it must not exit again
+		e.Message, e.escaped = item.message, true
+		nested.print(e)
+	}
+	if dropped > 0 {
+		e := nested.newEntry(LevelNotice)
+		e.Kind = KindSummary
+		e.msg(fmt.Sprintf("%d more not shown", dropped))
+	}
+}
+
+func summaryText(warnings, errors int) string {
+	if warnings == 0 && errors == 0 {
+		return "no warnings or errors"
+	}
+	var parts []string
+	if warnings > 0 {
+		parts = append(parts, plural(warnings, "warning"))
+	}
+	if errors > 0 {
+		parts = append(parts, plural(errors, "error"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Close finishes the logger: it reports pending repeats, logs the summary
+// when enabled and closes the sinks.
+func (l *Logger) Close() error {
+	l.flushRepeats()
+	if l.ExitSummary {
+		l.Summary()
+	}
+	err := l.closeSinks()
+	if l.config != nil {
+		err = errors.Join(err, l.config.close())
+	}
+	return err
+}
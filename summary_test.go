@@ -0,0 +1,104 @@
+package clog
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	l, _ := bufferLogger()
+	l.SetLogLevel(LevelDebug)
+	l.Warn().Msg("w")
+	l.Error().Msg("e")
+	task := l.Task("t")
+	task.Error().Msg("e")
+	task.Done()
+	l.Trace().Msg("filtered by level")
+
+	if got := l.Count(LevelError, LevelFatal); got != 2 {
+		t.Errorf("errors: %d", got)
+	}
+	if got := task.Count(LevelWarn); got != 1 {
+		t.Errorf("warnings seen from the task: %d", got)
+	}
+	if got := l.Count(LevelTrace, Level(-5), Level(99)); got != 0 {
+		t.Errorf("out of range levels: %d", got)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	l, b := bufferLogger()
+	l.WithCollector(true)
+	l.Info().Msg("fine")
+	for i := 0; i < 3; i++ {
+		l.Warn().Msg("disk at %d%%", 91)
+	}
+	l.Task("upload").Fail(errors.New("timeout"))
+	b.Reset()
+	l.Summary()
+
+	want := `✖ 3 warnings, 1 error
+  ├─ warnings: 3
+  └─ errors: 1
+  ✖ upload
+    └─ task: upload
+  ⚠ disk at 91%
+    └─ count: 3
+`
+	if got := uncolored(b.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// summaries are not counted themselves
+	if got := l.Count(LevelError); got != 1 {
+		t.Errorf("errors after Summary: %d", got)
+	}
+}
+
+func TestSummaryClean(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetFormatter(&JSONFormatter{})
+	l.Summary()
+	var got map[string]any
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["msg"] != "no warnings or errors" || got["level"] != "success" || got["kind"] != KindSummary {
+		t.Errorf("got %s", b.String())
+	}
+}
+
+func TestSummaryDropped(t *testing.T) {
+	l, b := bufferLogger()
+	l.WithCollector(true)
+	for i := 0; i < maxCollected+2; i++ {
+		l.Warn().Msg("w%d", i)
+	}
+	b.Reset()
+	l.Summary()
+	out := uncolored(b.String())
+	if !strings.Contains(out, "2 more not shown") || strings.Count(out, "⚠") != maxCollected+1 {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestCloseExitSummary(t *testing.T) {
+	l, b := bufferLogger()
+	if err := l.Close(); err != nil || b.Len() != 0 {
+		t.Errorf("Close without summary: %v %q", err, b.String())
+	}
+	l.WithExitSummary(true)
+	if !l.Collect {
+		t.Error("exit summary without the collector")
+	}
+	l.Error().Msg("bad")
+	b.Reset()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out := uncolored(b.String()); !strings.HasPrefix(out, "✖ 1 error\n  ├─ warnings: 0\n  └─ errors: 1\n  ✖ bad\n") {
+		t.Errorf("got %q", out)
+	}
+}
@@ -0,0 +1,331 @@
+package clog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SyslogFormat int
+
+const (
+	RFC5424 SyslogFormat = iota
+	RFC3164
+)
+
+type Facility int
+
+const (
+	FacilityKern   Facility = 0
+	FacilityUser   Facility = 1
+	FacilityDaemon Facility = 3
+	FacilityAuth   Facility = 4
+	FacilityLocal0 Facility = 16
+	FacilityLocal1 Facility = 17
+	FacilityLocal2 Facility = 18
+	FacilityLocal3 Facility = 19
+	FacilityLocal4 Facility = 20
+	FacilityLocal5 Facility = 21
+	FacilityLocal6 Facility = 22
+	FacilityLocal7 Facility = 23
+)
+
+// syslogSeverity maps levels to syslog severities. Ok is an informational
+// confirmation, Success a normal but significant condition.
+var syslogSeverity = [...]int{
+	LevelTrace:   7,
+	LevelDebug:   7,
+	LevelInfo:    6,
+	LevelNotice:  5,
+	LevelWarn:    4,
+	LevelOk:      6,
+	LevelSuccess: 5,
+	LevelError:   3,
+	LevelFatal:   2,
+}
+
+// DefaultSDID names the structured data element that carries the fields of
+// an entry in RFC 5424 messages.
+const DefaultSDID = "clog@32473"
+
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogSink sends entries to a syslog daemon over a unix socket, UDP or
+// TCP. Entries are queued and written by a goroutine, see Batching, so a slow
+// or unreachable daemon does not hold up logging. TCP connections use octet
+// counting framing (RFC 6587) and unix stream sockets end every message with
+// a newline. A failed write reconnects and is retried.
+type SyslogSink struct {
+	Batching
+	Format   SyslogFormat
+	Facility Facility
+	Hostname string
+	AppName  string
+	SDID     string
+	// WriteTimeout bounds connecting and each write to the daemon.
+	WriteTimeout time.Duration
+
+	network string
+	address string
+	batcher batcher[[]byte]
+	mu      sync.Mutex
+	conn    net.Conn
+	framing syslogFraming
+}
+
+type syslogFraming int
+
+const (
+	framingNone syslogFraming = iota
+	framingOctets
+	framingNewline
+)
+
+// DialSyslog connects to the syslog daemon at address. An empty network
+// uses the local daemon's socket.
+func DialSyslog(network, address string) (*SyslogSink, error) {
+	hostname, _ := os.Hostname()
+	batching := defaultBatching()
+	batching.BatchInterval = 100 * time.Millisecond
+	s := &SyslogSink{
+		Batching:     batching,
+		Format:       RFC5424,
+		Facility:     FacilityUser,
+		Hostname:     hostname,
+		AppName:      filepath.Base(os.Args[0]),
+		SDID:         DefaultSDID,
+		WriteTimeout: 5 * time.Second,
+		network:      network,
+		address:      address,
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SyslogSink) connect() error {
+	if s.network != "" {
+		conn, err := net.DialTimeout(s.network, s.address, s.WriteTimeout)
+		if err != nil {
+			return err
+		}
+		s.conn, s.framing = conn, framingOf(s.network)
+		return nil
+	}
+	for _, path := range syslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.DialTimeout(network, path, s.WriteTimeout); err == nil {
+				s.conn, s.framing = conn, framingOf(network)
+				return nil
+			}
+		}
+	}
+	return errors.New("clog: no local syslog socket")
+}
+
+func framingOf(network string) syslogFraming {
+	switch {
+	case strings.HasPrefix(network, "tcp"):
+		return framingOctets
+	case network == "unix":
+		return framingNewline
+	}
+	return framingNone
+}
+
+func (s *SyslogSink) WriteEntry(e *Entry) error {
+	var msg []byte
+	if s.Format == RFC3164 {
+		msg = s.rfc3164(e)
+	} else {
+		msg = s.rfc5424(e)
+	}
+	s.batcher.add(s.Batching, msg, s.send)
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (s *SyslogSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// send writes a batch, reconnecting first if the last write failed. Stream
+// messages go out in a single write, datagrams one by one, so a retry only
+// resends the datagrams from the one that failed.
+func (s *SyslogSink) send(msgs [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return &retryError{err: err}
+		}
+	}
+	if s.framing == framingNone {
+		for i, msg := range msgs {
+			if err := s.write(msg); err != nil {
+				s.disconnect()
+				return &partialError{sent: i, err: &retryError{err: err}}
+			}
+		}
+		return nil
+	}
+	var b bytes.Buffer
+	for _, msg := range msgs {
+		if s.framing == framingOctets {
+			fmt.Fprintf(&b, "%d ", len(msg))
+		}
+		if s.framing == framingNewline {
+			// a newline inside the message would split it in two
+			msg = bytes.ReplaceAll(msg, []byte("\n"), []byte(" "))
+		}
+		b.Write(msg)
+		if s.framing == framingNewline {
+			b.WriteByte('\n')
+		}
+	}
+	err := s.write(b.Bytes())
+	if err != nil {
+		s.disconnect()
+		return &retryError{err: err}
+	}
+	return nil
+}
+
+// disconnect drops a failed connection, so the next send reconnects.
+func (s *SyslogSink) disconnect() {
+	_ = s.conn.Close()
+	s.conn = nil
+}
+
+func (s *SyslogSink) write(p []byte) error {
+	if s.WriteTimeout > 0 {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+	}
+	_, err := s.conn.Write(p)
+	return err
+}
+
+// Close writes the queued entries and closes the connection.
+func (s *SyslogSink) Close() error {
+	s.batcher.close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogSink) priority(level Level) int {
+	severity := 6
+	if level >= 0 && int(level) < len(syslogSeverity) {
+		severity = syslogSeverity[level]
+	}
+	return int(s.Facility)*8 + severity
+}
+
+// rfc5424 formats e as
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID name="value"...] MSG
+func (s *SyslogSink) rfc5424(e *Entry) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - ",
+		s.priority(e.Level),
+		e.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeader(s.Hostname, 255),
+		syslogHeader(s.AppName, 48),
+		os.Getpid())
+
+	fields := sinkFields(e)
+	if len(fields) == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteByte('[')
+		b.WriteString(syslogName(s.SDID))
+		for _, field := range fields {
+			b.WriteByte(' ')
+			b.WriteString(syslogName(field.Key))
+			b.WriteString(`="`)
+			b.WriteString(syslogParamReplacer.Replace(field.Value.(string)))
+			b.WriteByte('"')
+		}
+		b.WriteByte(']')
+	}
+	if e.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(e.Message)
+	}
+	return b.Bytes()
+}
+
+// rfc3164 formats e as
+//
+//	<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG name=value...
+//
+// with the fields in logfmt, as the format has no structured data.
+func (s *SyslogSink) rfc3164(e *Entry) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>%s %s %s[%d]: ",
+		s.priority(e.Level),
+		e.Time.Format(time.Stamp),
+		syslogHeader(s.Hostname, 255),
+		syslogHeader(s.AppName, 32),
+		os.Getpid())
+
+	var fields bytes.Buffer
+	for _, field := range sinkFields(e) {
+		writeLogfmtField(&fields, logfmtKey(field.Key), field.Value.(string))
+	}
+	b.WriteString(strings.ReplaceAll(e.Message, "\n", " "))
+	if fields.Len() > 0 {
+		b.WriteByte(' ')
+		b.Write(fields.Bytes())
+	}
+	return b.Bytes()
+}
+
+var syslogParamReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogHeader returns a header field of printable ASCII, or the nil value.
+func syslogHeader(s string, limit int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	if len(s) > limit {
+		s = s[:limit]
+	}
+	return s
+}
+
+// syslogName returns a valid SD-ID or PARAM-NAME: at most 32 printable ASCII
+// characters other than '=', ' ', ']' and '"'.
+func syslogName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "_"
+	}
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
@@ -0,0 +1,277 @@
+package clog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func syslogEntry(msg string) *Entry {
+	return testEntry(LevelWarn, msg).Any("user", "ann")
+}
+
+// readOctets reads RFC 6587 octet counted messages until r ends.
+func readOctets(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	br := bufio.NewReader(r)
+	var msgs []string
+	for {
+		size, err := br.ReadString(' ')
+		if err == io.EOF {
+			return msgs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+		if err != nil {
+			t.Fatalf("bad frame length %q", size)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(br, msg); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, string(msg))
+	}
+}
+
+func acceptAll(t *testing.T, ln net.Listener) <-chan []byte {
+	t.Helper()
+	out := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			out <- nil
+			return
+		}
+		defer conn.Close()
+		p, _ := io.ReadAll(conn)
+		out <- p
+	}()
+	return out
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := acceptAll(t, ln)
+
+	s, err := DialSyslog("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Hostname, s.AppName = "host", "app"
+	s.WriteEntry(syslogEntry("first\nline"))
+	s.WriteEntry(syslogEntry("second"))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := readOctets(t, bytes.NewReader(<-received))
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages: %q", len(msgs), msgs)
+	}
+	want := `<12>1 2024-05-01T12:00:00.000000Z host app `
+	if !strings.HasPrefix(msgs[0], want) {
+		t.Errorf("got %q, want prefix %q", msgs[0], want)
+	}
+	if !strings.HasSuffix(msgs[0], ` [clog@32473 user="ann"] first`+"\n"+`line`) {
+		t.Errorf("got %q", msgs[0])
+	}
+	if !strings.HasSuffix(msgs[1], "] second") {
+		t.Errorf("got %q", msgs[1])
+	}
+}
+
+func TestSyslogUnixStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	received := acceptAll(t, ln)
+
+	s, err := DialSyslog("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Format = RFC3164
+	s.Hostname, s.AppName = "host", "app"
+	s.WriteEntry(syslogEntry("first"))
+	s.WriteEntry(syslogEntry("second"))
+	s.Close()
+
+	lines := strings.Split(strings.TrimSuffix(string(<-received), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %q", len(lines), lines)
+	}
+	for i, msg := range []string{"first", "second"} {
+		if !strings.HasPrefix(lines[i], "<12>May  1 12:00:00 host app[") ||
+			!strings.HasSuffix(lines[i], "]: "+msg+" user=ann") {
+			t.Errorf("line %d: got %q", i, lines[i])
+		}
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := DialSyslog("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.WriteEntry(syslogEntry("first"))
+	s.WriteEntry(syslogEntry("second"))
+	s.Close()
+
+	buf := make([]byte, 2048)
+	for _, msg := range []string{"first", "second"} {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); !strings.HasSuffix(got, "] "+msg) {
+			t.Errorf("got %q, want message %q", got, msg)
+		}
+	}
+}
+
+func TestSyslogReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s, err := DialSyslog("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RetryBackoff = 10 * time.Millisecond
+	first, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	received := acceptAll(t, ln)
+	// the write on the dropped connection may succeed before the reset is
+	// noticed, so keep logging until the new connection gets something
+	for i := 0; i < 50; i++ {
+		s.WriteEntry(syslogEntry("again"))
+		time.Sleep(20 * time.Millisecond)
+	}
+	s.Close()
+	msgs := readOctets(t, bytes.NewReader(<-received))
+	if len(msgs) == 0 || !strings.HasSuffix(msgs[len(msgs)-1], "] again") {
+		t.Fatalf("got %q after reconnecting", msgs)
+	}
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s := &SyslogSink{Facility: FacilityLocal0, Hostname: "my host", AppName: "app", SDID: DefaultSDID}
+	pid := strconv.Itoa(os.Getpid())
+
+	e := testEntry(LevelError, "failed").Any("path", `C:\a "b" [c]`)
+	want := `<131>1 2024-05-01T12:00:00.000000Z myhost app ` + pid +
+		` - [clog@32473 path="C:\\a \"b\" [c\]"] failed`
+	if got := string(s.rfc5424(e)); got != want {
+		t.Errorf("rfc5424:\ngot  %q\nwant %q", got, want)
+	}
+
+	e = testEntry(LevelInfo, "two\nlines")
+	want = `<134>1 2024-05-01T12:00:00.000000Z myhost app ` + pid + ` - - two` + "\n" + `lines`
+	if got := string(s.rfc5424(e)); got != want {
+		t.Errorf("rfc5424 without fields:\ngot  %q\nwant %q", got, want)
+	}
+	want = `<134>May  1 12:00:00 myhost app[` + pid + `]: two lines`
+	if got := string(s.rfc3164(e)); got != want {
+		t.Errorf("rfc3164:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+// listenUnixgram returns a datagram socket in a temporary directory.
+func listenUnixgram(t *testing.T) (net.PacketConn, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, path
+}
+
+func readDatagram(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	conn, path := listenUnixgram(t)
+	s, err := DialSyslog("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.WriteEntry(syslogEntry("first\nline"))
+	s.WriteEntry(syslogEntry("second"))
+	s.Close()
+
+	// every datagram is a message of its own, newlines included
+	for _, msg := range []string{"first\nline", "second"} {
+		if got := readDatagram(t, conn); !strings.HasSuffix(got, "] "+msg) {
+			t.Errorf("got %q, want message %q", got, msg)
+		}
+	}
+}
+
+// TestSyslogFatal checks that a Fatal entry is shipped before the process
+// exits, although the sink only sends once an hour.
+func TestSyslogFatal(t *testing.T) {
+	if path := os.Getenv("CLOG_TEST_SYSLOG"); path != "" {
+		s, err := DialSyslog("unixgram", path)
+		if err != nil {
+			os.Exit(2)
+		}
+		s.BatchInterval = time.Hour
+		l := New()
+		l.Writer = io.Discard
+		l.AddSink(s)
+		l.Fatal().Msg("giving up")
+		return
+	}
+
+	conn, path := listenUnixgram(t)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSyslogFatal$")
+	cmd.Env = append(os.Environ(), "CLOG_TEST_SYSLOG="+path)
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); !ok || exit.ExitCode() != 1 {
+		t.Fatalf("got %v, want exit status 1", err)
+	}
+	if got := readDatagram(t, conn); !strings.HasSuffix(got, " - giving up") {
+		t.Errorf("got %q", got)
+	}
+}
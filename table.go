@@ -0,0 +1,239 @@
+package clog
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const defaultMaxRows = 50
+
+// Table is a list of rows logged under a message. The pretty formatter draws
+// it with aligned columns, the structured formatters emit it as an array of
+// objects keyed by header.
+type Table struct {
+	Headers []string
+	Rows    [][]any
+}
+
+func (e *Entry) Table(headers []string, rows [][]any) *Entry {
+	return e.Any("table", Table{Headers: headers, Rows: rows})
+}
+
+func (t Table) Objects() []Object {
+	objects := make([]Object, len(t.Rows))
+	for i, row := range t.Rows {
+		obj := make(Object, 0, len(t.Headers))
+		for j, header := range t.Headers {
+			var value any
+			if j < len(row) {
+				value = row[j]
+			}
+			obj.Add(header, value)
+		}
+		objects[i] = obj
+	}
+	return objects
+}
+
+func (t Table) MarshalJSON() ([]byte, error) {
+	return marshalJSON(t.Objects())
+}
+
+// tableOf returns value as a table when it is one, or when it is a slice or
+// array of structs, which reads better as rows than as a subtree per item.
+// Items that resolve to objects, such as slog.LogValuers, are tabulated by
+// what they resolve to, so their columns hide what the valuer hides.
+func tableOf(value any) (Table, bool) {
+	switch value := value.(type) {
+	case Table:
+		return value, len(value.Headers) > 0
+	case Object:
+		// a slice of Arguments, but a single object
+		return Table{}, false
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Len() == 0 {
+		return Table{}, false
+	}
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() == reflect.Interface || implements(elem, valuerType) || implements(elem, marshalerType) {
+		return objectTable(rv)
+	}
+	if elem.Kind() != reflect.Struct || !hasExportedFields(elem) || implements(elem, stringerType) {
+		return Table{}, false
+	}
+
+	var t Table
+	var fields []int
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		name, _, hasOpts := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" && !hasOpts {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		t.Headers = append(t.Headers, name)
+		fields = append(fields, i)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		if item.Kind() == reflect.Pointer {
+			if item.IsNil() {
+				continue
+			}
+			item = item.Elem()
+		}
+		row := make([]any, len(fields))
+		for j, field := range fields {
+			row[j] = item.Field(field).Interface()
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, true
+}
+
+// objectTable resolves the items of rv and tabulates them when each is an
+// object, with a column per key in the order the keys first appear.
+func objectTable(rv reflect.Value) (Table, bool) {
+	var t Table
+	columns := map[string]int{}
+	var objects []Object
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		if (item.Kind() == reflect.Pointer || item.Kind() == reflect.Interface) && item.IsNil() {
+			continue
+		}
+		obj, ok := resolve(item.Interface()).(Object)
+		if !ok || len(obj) == 0 {
+			return Table{}, false
+		}
+		for _, arg := range obj {
+			if _, ok := columns[arg.Key]; !ok {
+				columns[arg.Key] = len(t.Headers)
+				t.Headers = append(t.Headers, arg.Key)
+			}
+		}
+		objects = append(objects, obj)
+	}
+	for _, obj := range objects {
+		row := make([]any, len(t.Headers))
+		for _, arg := range obj {
+			row[columns[arg.Key]] = arg.Value
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, len(t.Headers) > 0
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+var (
+	stringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	marshalerType = reflect.TypeOf((*ObjectMarshaler)(nil)).Elem()
+	valuerType    = reflect.TypeOf((*slog.LogValuer)(nil)).Elem()
+)
+
+// writeTable draws a table below the current node, each line prefixed with
+// cont. Columns shrink, widest first, until the table fits the output.
+func (t *treeWriter) writeTable(cont string, lead int, table Table) {
+	rows := table.Rows
+	more := 0
+	if len(rows) > defaultMaxRows {
+		rows, more = rows[:defaultMaxRows], len(rows)-defaultMaxRows
+	}
+
+	cells := make([][]string, len(rows))
+	numeric := make([]bool, len(table.Headers))
+	widths := make([]int, len(table.Headers))
+	for j, header := range table.Headers {
+		widths[j] = runewidth.StringWidth(t.logger.sanitize(header))
+		numeric[j] = len(rows) > 0
+	}
+	for i, row := range rows {
+		cells[i] = make([]string, len(table.Headers))
+		for j := range table.Headers {
+			var value any
+			if j < len(row) {
+				value = resolve(row[j])
+			}
+			cells[i][j] = strings.ReplaceAll(t.logger.valueString(value), "\n", " ")
+			widths[j] = max(widths[j], runewidth.StringWidth(cells[i][j]))
+			numeric[j] = numeric[j] && isNumber(value)
+		}
+	}
+
+	if t.width > 0 {
+		avail := t.width - lead
+		for total := tableWidth(widths); total > avail; total-- {
+			widest := 0
+			for j := range widths {
+				if widths[j] > widths[widest] {
+					widest = j
+				}
+			}
+			if widths[widest] <= 3 {
+				break
+			}
+			widths[widest]--
+		}
+	}
+
+	line := func(values []string, render func(...string) string) {
+		t.b.WriteString(cont)
+		for j, value := range values {
+			if j > 0 {
+				t.b.WriteString("  ")
+			}
+			value = runewidth.Truncate(value, widths[j], "…")
+			if numeric[j] {
+				value = runewidth.FillLeft(value, widths[j])
+			} else if j < len(values)-1 {
+				value = runewidth.FillRight(value, widths[j])
+			}
+			t.b.WriteString(render(value))
+		}
+	}
+
+	headers := make([]string, len(table.Headers))
+	for j, header := range table.Headers {
+		headers[j] = t.logger.sanitize(header)
+	}
+	line(headers, t.headerStyle.Render)
+	for _, row := range cells {
+		line(row, plain)
+	}
+	if more > 0 {
+		t.b.WriteString(cont)
+		t.b.WriteString(t.palette.values.More.Render(fmt.Sprintf("...%d more", more)))
+	}
+}
+
+func tableWidth(widths []int) int {
+	total := 2 * max(len(widths)-1, 0)
+	for _, w := range widths {
+		total += w
+	}
+	return total
+}
+
+func isNumber(value any) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package clog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type server struct {
+	Name  string `json:"name"`
+	Port  int    `json:"port"`
+	Token string `json:"token"`
+}
+
+type host string
+
+func (h host) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("host", string(h)), slog.Int("len", len(h)))
+}
+
+func TestTableOutput(t *testing.T) {
+	l, b := bufferLogger()
+	l.Info().Table([]string{"name", "count"}, [][]any{
+		{"apples", 3},
+		{"kiwis", 120},
+	}).Msg("stock")
+	want := `• stock
+  └─ table
+     name    count
+     apples      3
+     kiwis     120
+`
+	if got := uncolored(b.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTableOfStructs(t *testing.T) {
+	l, b := bufferLogger()
+	l.Info().Any("servers", []server{{"a", 80, "t1"}, {"bb", 8080, "t2"}}).Msg("up")
+	want := `• up
+  └─ servers
+     name  port  token
+     a       80  ****
+     bb    8080  ****
+`
+	if got := uncolored(b.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	table, ok := tableOf([]host{"x", "yy"})
+	if !ok || strings.Join(table.Headers, ",") != "host,len" || table.Rows[1][0] != "yy" {
+		t.Errorf("valuers: %#v", table)
+	}
+	for _, value := range []any{[]int{1}, Object{{"a", 1}}, []server{}, "s"} {
+		if _, ok := tableOf(value); ok {
+			t.Errorf("%#v tabulated", value)
+		}
+	}
+}
+
+func TestTableLimits(t *testing.T) {
+	rows := make([][]any, defaultMaxRows+5)
+	for i := range rows {
+		rows[i] = []any{i, strings.Repeat("x", 60)}
+	}
+	l, b := bufferLogger()
+	l.SetWidth(40).Info().Table([]string{"n", "text"}, rows).Msg("big")
+	out := uncolored(b.String())
+	if !strings.Contains(out, "...5 more") {
+		t.Errorf("rows not capped: %q", out)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if w := len([]rune(line)); w > 40 {
+			t.Errorf("line wider than 40: %q", line)
+		}
+	}
+}
+
+func TestTableStructured(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetFormatter(&JSONFormatter{})
+	l.Info().Table([]string{"user", "password"}, [][]any{{"ann", "p"}}).Msg("t")
+	var got struct{ Table []map[string]any }
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Table) != 1 || got.Table[0]["user"] != "ann" || got.Table[0]["password"] != "****" {
+		t.Errorf("got %s", b.String())
+	}
+
+	b.Reset()
+	l.SetFormatter(&LogfmtFormatter{})
+	l.Info().Table([]string{"a"}, [][]any{{1}}).Msg("t")
+	if !strings.Contains(b.String(), `table="[{\"a\":1}]"`) {
+		t.Errorf("logfmt: %s", b.String())
+	}
+}
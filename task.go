@@ -0,0 +1,138 @@
+package clog
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var taskIDs atomic.Uint64
+
+const (
+	TaskStart = "start"
+	TaskDone  = "done"
+	TaskFail  = "fail"
+	TaskSkip  = "skip"
+)
+
+// TaskLogger is a scoped logger for one step of a longer run. Entries logged
+// through it are indented one level below the task line, and closing it
+// with Done, Fail or Skip reports how long the step took.
+type TaskLogger struct {
+	*Logger
+	id     uint64
+	name   string
+	parent *TaskLogger
+	owner  *Logger
+	start  time.Time
+	closed atomic.Bool
+}
+
+func Task(name string) *TaskLogger {
+	return logger.Task(name)
+}
+
+func (l *Logger) Task(name string) *TaskLogger {
+	t := &TaskLogger{
+		id:     taskIDs.Add(1),
+		name:   name,
+		parent: l.task,
+		owner:  l,
+		start:  time.Now(),
+	}
+	t.Logger = l.clone()
+	t.Logger.indent++
+	t.Logger.task = t
+	l.taskEntry(t, LevelInfo, TaskStart).msg(name)
+	return t
+}
+
+func (t *TaskLogger) ID() uint64 {
+	return t.id
+}
+
+func (t *TaskLogger) ParentID() uint64 {
+	if t.parent == nil {
+		return 0
+	}
+	return t.parent.id
+}
+
+func (t *TaskLogger) Name() string {
+	return t.name
+}
+
+func (t *TaskLogger) Done() {
+	if e := t.finish(LevelSuccess, TaskDone); e != nil {
+		e.msg(t.name)
+	}
+}
+
+func (t *TaskLogger) Fail(err error) {
+	if e := t.finish(LevelError, TaskFail); e != nil {
+		e.Err(err).msg(t.name)
+	}
+}
+
+func (t *TaskLogger) Skip(reason string) {
+	if e := t.finish(LevelNotice, TaskSkip); e != nil {
+		e.Any("reason", reason).msg(t.name)
+	}
+}
+
+// finish returns the closing entry, or nil when the task was already closed.
+func (t *TaskLogger) finish(level Level, event string) *Entry {
+	if !t.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	e := t.owner.taskEntry(t, level, event)
+	e.Elapsed = time.Since(t.start)
+	return e
+}
+
+func (l *Logger) taskEntry(t *TaskLogger, level Level, event string) *Entry {
+	e := l.newEntry(level)
+	e.Task, e.Event = t, event
+	return e
+}
+
+func formatElapsed(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		d = d.Round(time.Microsecond)
+	case d < time.Second:
+		d = d.Round(time.Millisecond)
+	default:
+		d = d.Round(100 * time.Millisecond)
+	}
+	return fmt.Sprintf("(%s)", d)
+}
+
+// eventFields identifies the kind and task of an entry, how long it took and
+// how often it repeated for the structured formatters, which cannot show
+// nesting through indentation.
+func eventFields(e *Entry) []Argument {
+	var fields []Argument
+	if e.Kind != "" {
+		fields = append(fields, Argument{Key: "kind", Value: e.Kind})
+	}
+	if e.Task != nil {
+		fields = append(fields, Argument{Key: "task_id", Value: e.Task.ID()})
+		if parent := e.Task.ParentID(); parent != 0 {
+			fields = append(fields, Argument{Key: "task_parent_id", Value: parent})
+		}
+	}
+	if e.Event != "" {
+		fields = append(fields, Argument{Key: "task_event", Value: e.Event})
+	}
+	if e.Elapsed > 0 {
+		fields = append(fields, Argument{Key: "duration_ms", Value: e.Elapsed.Milliseconds()})
+	}
+	if e.Repeated > 0 {
+		fields = append(fields, Argument{Key: "repeated", Value: e.Repeated})
+	}
+	if e.Backfilled {
+		fields = append(fields, Argument{Key: "backfilled", Value: true})
+	}
+	return fields
+}
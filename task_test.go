@@ -0,0 +1,105 @@
+package clog
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var elapsed = regexp.MustCompile(`\([0-9.]+[µnm]?s\)`)
+
+func TestTaskOutput(t *testing.T) {
+	l, b := bufferLogger()
+	task := l.Task("deploy")
+	task.Info().Msg("uploading")
+	task.Task("migrate").Skip("up to date")
+	task.Fail(errors.New("boom"))
+	task.Done()
+
+	got := elapsed.ReplaceAllString(uncolored(b.String()), "(t)")
+	want := `• deploy
+  • uploading
+  • migrate
+  • migrate (t)
+    └─ reason: up to date
+✖ deploy (t)
+  └─ err: boom
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTaskFields(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetFormatter(&JSONFormatter{})
+	task := l.Task("outer")
+	inner := task.Task("inner")
+	inner.Done()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		lines = append(lines, m)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	if lines[0]["task_id"] != float64(task.ID()) || lines[0]["task_event"] != TaskStart {
+		t.Errorf("start: %v", lines[0])
+	}
+	if _, ok := lines[0]["duration_ms"]; ok {
+		t.Errorf("start has a duration: %v", lines[0])
+	}
+	done := lines[2]
+	if done["task_id"] != float64(inner.ID()) || done["task_parent_id"] != float64(task.ID()) ||
+		done["task_event"] != TaskDone || done["level"] != "success" {
+		t.Errorf("done: %v", done)
+	}
+	if _, ok := done["duration_ms"]; !ok {
+		t.Errorf("done has no duration: %v", done)
+	}
+}
+
+func TestZeroLoggerTask(t *testing.T) {
+	var b strings.Builder
+	l := &Logger{Writer: &b}
+	task := l.Task("t")
+	task.Info().Msg("inside")
+	task.Done()
+	if got := strings.Count(uncolored(b.String()), "\n"); got != 3 {
+		t.Errorf("got %q", b.String())
+	}
+}
+
+func TestCloneCopiesSettings(t *testing.T) {
+	l := New()
+	rv := reflect.ValueOf(l).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		if !rv.Type().Field(i).IsExported() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Int:
+			field.SetInt(7)
+		case reflect.String:
+			field.SetString("x")
+		}
+	}
+	c := reflect.ValueOf(l.clone()).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		name := rv.Type().Field(i).Name
+		if rv.Type().Field(i).IsExported() && !reflect.DeepEqual(c.Field(i).Interface(), rv.Field(i).Interface()) {
+			t.Errorf("clone dropped %s", name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package clog
+
+import (
+	"io"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+)
+
+type fdWriter interface {
+	Fd() uintptr
+}
+
+var (
+	termWidths  sync.Map
+	watchResize sync.Once
+)
+
+// terminalWidth reports the column count of the terminal behind w, or 0 when
+// w is not a terminal. Widths are cached until the terminal is resized.
+func terminalWidth(w io.Writer) int {
+	f, ok := w.(fdWriter)
+	if !ok {
+		return 0
+	}
+	fd := f.Fd()
+	if width, ok := termWidths.Load(fd); ok && cacheWidths {
+		return width.(int)
+	}
+	watchResize.Do(startResizeWatcher)
+	width := queryWidth(fd)
+	termWidths.Store(fd, width)
+	return width
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(fdWriter)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+func resetTerminalWidths() {
+	termWidths.Range(func(key, _ any) bool {
+		termWidths.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package clog
+
+const cacheWidths = false
+
+func queryWidth(fd uintptr) int {
+	return 0
+}
+
+func startResizeWatcher() {}
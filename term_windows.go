@@ -0,0 +1,20 @@
+//go:build windows
+
+package clog
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+func queryWidth(fd uintptr) int {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(fd), &info); err != nil {
+		return 0
+	}
+	return int(info.Window.Right - info.Window.Left + 1)
+}
+
+// Windows has no SIGWINCH, so the width is queried for every entry instead.
+const cacheWidths = false
+
+func startResizeWatcher() {}
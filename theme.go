@@ -0,0 +1,129 @@
+package clog
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is the color of each level, and of what is not tied to a level:
+// muted text such as tree guides, times and nil values, numbers, booleans,
+// box borders and the filled part of progress bars.
+type Theme struct {
+	Levels [len(levelText)]lipgloss.Color
+	Muted  lipgloss.Color
+	Number lipgloss.Color
+	Bool   lipgloss.Color
+	Border lipgloss.Color
+	Filled lipgloss.Color
+}
+
+// Themes are the themes SetTheme can switch to, by name.
+var Themes = map[string]Theme{
+	"default": {
+		Levels: [...]lipgloss.Color{
+			LevelTrace:   "51",
+			LevelDebug:   "102",
+			LevelInfo:    "",
+			LevelNotice:  "15",
+			LevelWarn:    "214",
+			LevelOk:      "27",
+			LevelSuccess: "47",
+			LevelError:   "196",
+			LevelFatal:   "160",
+		},
+		Muted:  "240",
+		Number: "141",
+		Bool:   "208",
+		Border: "63",
+		Filled: "47",
+	},
+	// darker colors that stay readable on a light background
+	"light": {
+		Levels: [...]lipgloss.Color{
+			LevelTrace:   "30",
+			LevelDebug:   "243",
+			LevelInfo:    "",
+			LevelNotice:  "0",
+			LevelWarn:    "166",
+			LevelOk:      "25",
+			LevelSuccess: "28",
+			LevelError:   "160",
+			LevelFatal:   "124",
+		},
+		Muted:  "245",
+		Number: "91",
+		Bool:   "130",
+		Border: "61",
+		Filled: "28",
+	},
+	// no colors at all, only bold, faint and italic text
+	"mono": {},
+}
+
+// SetTheme colors the levels in Styles, and the value, block and progress
+// styles, after the named theme. Loggers with a Theme of their own keep it.
+func SetTheme(name string) error {
+	theme, ok := Themes[name]
+	if !ok {
+		return fmt.Errorf("clog: unknown theme %q", name)
+	}
+	p := theme.recolor(globalPalette())
+	Styles, gray, divide, guide = p.levels, p.muted, p.divide, p.guide
+	ValueStyles, BlockStyles, ProgressStyles = p.values, p.block, p.progress
+	return nil
+}
+
+// palette holds the styles entries and live items are drawn with.
+type palette struct {
+	levels   [len(Styles)]LevelStyle
+	muted    lipgloss.Color
+	divide   lipgloss.Style
+	guide    lipgloss.Style
+	values   ValueStyle
+	block    BlockStyle
+	progress ProgressStyle
+}
+
+func globalPalette() *palette {
+	return &palette{
+		levels:   Styles,
+		muted:    gray,
+		divide:   divide,
+		guide:    guide,
+		values:   ValueStyles,
+		block:    BlockStyles,
+		progress: ProgressStyles,
+	}
+}
+
+// recolor returns p in the colors of t. Styles are copied before they are
+// changed, as a lipgloss style shares its properties with its copies.
+func (t *Theme) recolor(p *palette) *palette {
+	c := *p
+	for level, color := range t.Levels {
+		c.levels[level].Color = color
+	}
+	c.muted = t.Muted
+	c.divide = p.divide.Copy().Foreground(t.Muted)
+	c.guide = p.guide.Copy().Foreground(t.Muted)
+	c.values.Number = p.values.Number.Copy().Foreground(t.Number)
+	c.values.Bool = p.values.Bool.Copy().Foreground(t.Bool)
+	c.values.Nil = p.values.Nil.Copy().Foreground(t.Muted)
+	c.values.More = p.values.More.Copy().Foreground(t.Muted)
+	c.block.Rule = p.block.Rule.Copy().Foreground(t.Muted)
+	c.block.Border = t.Border
+	c.progress.Filled = p.progress.Filled.Copy().Foreground(t.Filled)
+	c.progress.Empty = p.progress.Empty.Copy().Foreground(t.Muted)
+	c.progress.Info = p.progress.Info.Copy().Foreground(t.Muted)
+	return &c
+}
+
+// palette returns the styles l draws with: the global ones, recolored by
+// l.Theme when it is set.
+func (l *Logger) palette() *palette {
+	if l.Theme == nil {
+		return globalPalette()
+	}
+	return l.Theme.recolor(globalPalette())
+}
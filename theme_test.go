@@ -0,0 +1,44 @@
+package clog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMonoTheme(t *testing.T) {
+	render := func() string {
+		var b bytes.Buffer
+		l := &Logger{Writer: &b, ShowTime: true, Width: -1}
+		l.Warn().Any("count", 3).Any("ok", true).Any("none", nil).Msg("values")
+		return b.String()
+	}
+	defer SetTheme("default")
+
+	if out := render(); !strings.Contains(out, "\x1b[38;") {
+		t.Fatalf("default theme without colors: %q", out)
+	}
+	if err := SetTheme("mono"); err != nil {
+		t.Fatal(err)
+	}
+	if out := render(); strings.Contains(out, "\x1b[38;") {
+		t.Errorf("mono theme with colors: %q", out)
+	}
+	if err := SetTheme("nope"); err == nil {
+		t.Error("unknown theme accepted")
+	}
+}
+
+func TestLoggerTheme(t *testing.T) {
+	mono := Themes["mono"]
+	var colored, plain bytes.Buffer
+	(&Logger{Writer: &colored, Width: -1}).Warn().Any("count", 3).Msg("values")
+	(&Logger{Writer: &plain, Width: -1, Theme: &mono}).Warn().Any("count", 3).Msg("values")
+
+	if strings.Contains(plain.String(), "\x1b[38;") {
+		t.Errorf("mono logger with colors: %q", plain.String())
+	}
+	if !strings.Contains(colored.String(), "\x1b[38;") {
+		t.Errorf("default logger lost its colors: %q", colored.String())
+	}
+}
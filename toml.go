@@ -0,0 +1,408 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML reads the TOML that configuration files use into maps, slices,
+// strings, numbers and bools: tables, arrays of tables, dotted keys, inline
+// tables and arrays. Dates and times are left out, as config takes them as
+// strings.
+func parseTOML(data string) (map[string]any, error) {
+	p := &tomlParser{s: data, root: map[string]any{}, defined: map[string]bool{}}
+	if err := p.parse(); err != nil {
+		line := strings.Count(p.s[:p.pos], "\n") + 1
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	return p.root, nil
+}
+
+type tomlParser struct {
+	s       string
+	pos     int
+	root    map[string]any
+	table   map[string]any
+	defined map[string]bool
+}
+
+func (p *tomlParser) parse() error {
+	p.table = p.root
+	for {
+		p.skipSpace(true)
+		if p.pos == len(p.s) {
+			return nil
+		}
+		var err error
+		if p.s[p.pos] == '[' {
+			err = p.header()
+		} else {
+			err = p.keyValue(p.table)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// header reads a [table] or [[array of tables]] and makes it current.
+func (p *tomlParser) header() error {
+	array := strings.HasPrefix(p.s[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipSpace(false)
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace(false)
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.s[p.pos:], closing) {
+		return fmt.Errorf("expected %q after table name", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.walk(p.root, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if array {
+		list, ok := parent[last].([]any)
+		if _, exists := parent[last]; exists && !ok {
+			return fmt.Errorf("%s is not an array of tables", strings.Join(keys, "."))
+		}
+		p.table = map[string]any{}
+		parent[last] = append(list, p.table)
+		return nil
+	}
+	name := strings.Join(keys, "\x00")
+	if p.defined[name] {
+		return fmt.Errorf("table %s is defined twice", strings.Join(keys, "."))
+	}
+	p.defined[name] = true
+	p.table, err = p.walk(parent, keys[len(keys)-1:])
+	return err
+}
+
+// walk returns the table at keys under table, creating the missing ones.
+// A key naming an array of tables walks into its last table.
+func (p *tomlParser) walk(table map[string]any, keys []string) (map[string]any, error) {
+	for _, key := range keys {
+		switch v := table[key].(type) {
+		case nil:
+			next := map[string]any{}
+			table[key] = next
+			table = next
+		case map[string]any:
+			table = v
+		case []any:
+			var last map[string]any
+			if len(v) > 0 {
+				last, _ = v[len(v)-1].(map[string]any)
+			}
+			if last == nil {
+				return nil, fmt.Errorf("%s is not a table", key)
+			}
+			table = last
+		default:
+			return nil, fmt.Errorf("%s is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+func (p *tomlParser) keyValue(table map[string]any) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace(false)
+	if p.pos == len(p.s) || p.s[p.pos] != '=' {
+		return fmt.Errorf("expected = after %s", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipSpace(false)
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	table, err = p.walk(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := table[last]; ok {
+		return fmt.Errorf("%s is defined twice", strings.Join(keys, "."))
+	}
+	table[last] = value
+	return nil
+}
+
+// key reads a bare, quoted or dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		if p.pos == len(p.s) {
+			return nil, errors.New("expected a key")
+		}
+		switch c := p.s[p.pos]; {
+		case c == '"' || c == '\'':
+			key, err := p.string()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		case isBareKey(c):
+			start := p.pos
+			for p.pos < len(p.s) && isBareKey(p.s[p.pos]) {
+				p.pos++
+			}
+			keys = append(keys, p.s[start:p.pos])
+		default:
+			return nil, fmt.Errorf("unexpected %q in key", c)
+		}
+		p.skipSpace(false)
+		if p.pos == len(p.s) || p.s[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+		p.skipSpace(false)
+	}
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() (any, error) {
+	if p.pos == len(p.s) {
+		return nil, errors.New("expected a value")
+	}
+	switch c := p.s[p.pos]; {
+	case c == '"' || c == '\'':
+		return p.string()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(p.s[p.pos:], "true"):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(p.s[p.pos:], "false"):
+		p.pos += 5
+		return false, nil
+	}
+	return p.number()
+}
+
+func (p *tomlParser) number() (any, error) {
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("+-._:0123456789abcdefinoxABCDEFINTZ", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	text := p.s[start:p.pos]
+	if text == "" {
+		return nil, fmt.Errorf("unexpected %q", p.s[p.pos])
+	}
+	s := strings.ReplaceAll(text, "_", "")
+	if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	if strings.ContainsAny(text, ":T") || strings.Count(text, "-") > 1 {
+		return nil, fmt.Errorf("dates are not supported, quote %s as a string", text)
+	}
+	return nil, fmt.Errorf("invalid value %s", text)
+}
+
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++
+	list := []any{}
+	for {
+		p.skipSpace(true)
+		if p.pos < len(p.s) && p.s[p.pos] == ']' {
+			p.pos++
+			return list, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		p.skipSpace(true)
+		if p.pos == len(p.s) {
+			return nil, errors.New("unterminated array")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected , or ] in array, found %q", p.s[p.pos])
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.pos++
+	table := map[string]any{}
+	p.skipSpace(false)
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		p.skipSpace(false)
+		if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		if p.pos == len(p.s) {
+			return nil, errors.New("unterminated inline table")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, fmt.Errorf("expected , or } in inline table, found %q", p.s[p.pos])
+		}
+	}
+}
+
+// string reads a basic or literal string, on one line or on several.
+func (p *tomlParser) string() (string, error) {
+	quote := p.s[p.pos : p.pos+1]
+	multiline := strings.HasPrefix(p.s[p.pos:], quote+quote+quote)
+	if multiline {
+		quote = quote + quote + quote
+		p.pos += 3
+		// a newline right after the opening quotes is not part of the string
+		if strings.HasPrefix(p.s[p.pos:], "\r\n") {
+			p.pos += 2
+		} else if strings.HasPrefix(p.s[p.pos:], "\n") {
+			p.pos++
+		}
+	} else {
+		p.pos++
+	}
+	literal := quote[0] == '\''
+
+	var b strings.Builder
+	for {
+		if p.pos == len(p.s) || !multiline && p.s[p.pos] == '\n' {
+			return "", errors.New("unterminated string")
+		}
+		if strings.HasPrefix(p.s[p.pos:], quote) {
+			p.pos += len(quote)
+			return b.String(), nil
+		}
+		c := p.s[p.pos]
+		if c != '\\' || literal {
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+		p.pos++
+		if p.pos == len(p.s) {
+			return "", errors.New("unterminated string")
+		}
+		c = p.s[p.pos]
+		p.pos++
+		switch c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'e':
+			b.WriteByte(0x1b)
+		case '"', '\\':
+			b.WriteByte(c)
+		case 'u', 'U':
+			size := 4
+			if c == 'U' {
+				size = 8
+			}
+			if p.pos+size > len(p.s) {
+				return "", errors.New("invalid unicode escape")
+			}
+			n, err := strconv.ParseUint(p.s[p.pos:p.pos+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(n)) {
+				return "", fmt.Errorf("invalid unicode escape \\%c%s", c, p.s[p.pos:p.pos+size])
+			}
+			b.WriteRune(rune(n))
+			p.pos += size
+		case '\n', ' ', '\t', '\r':
+			// a backslash at the end of a line joins it with the next
+			if !multiline {
+				return "", fmt.Errorf("invalid escape \\%q", c)
+			}
+			for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+				p.pos++
+			}
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", c)
+		}
+	}
+}
+
+// skipSpace skips spaces and, with newlines, blank lines and comments.
+func (p *tomlParser) skipSpace(newlines bool) {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t':
+		case '\r', '\n':
+			if !newlines {
+				return
+			}
+		case '#':
+			if !newlines {
+				return
+			}
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+// endLine checks that nothing but a comment follows on the line.
+func (p *tomlParser) endLine() error {
+	p.skipSpace(false)
+	if p.pos < len(p.s) && p.s[p.pos] == '#' {
+		for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+	if strings.HasPrefix(p.s[p.pos:], "\r\n") || strings.HasPrefix(p.s[p.pos:], "\n") || p.pos == len(p.s) {
+		return nil
+	}
+	return fmt.Errorf("unexpected %q after value", p.s[p.pos])
+}
@@ -0,0 +1,174 @@
+package clog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		toml string
+		want map[string]any
+	}{
+		{
+			name: "values",
+			toml: `
+# a comment
+level = "debug"   # trailing comment
+watch = true
+caller = false
+size = 1_000
+hex = 0xff
+ratio = 0.5
+exp = 1e3
+neg = -3
+`,
+			want: map[string]any{
+				"level": "debug", "watch": true, "caller": false,
+				"size": int64(1000), "hex": int64(255), "ratio": 0.5, "exp": 1000.0, "neg": int64(-3),
+			},
+		},
+		{
+			name: "tables",
+			toml: `
+[redact]
+keys = ["password", "token"]
+
+[components]
+db = "warn"
+
+[a.b]
+c = 1
+[a]
+d = 2
+`,
+			want: map[string]any{
+				"redact":     map[string]any{"keys": []any{"password", "token"}},
+				"components": map[string]any{"db": "warn"},
+				"a":          map[string]any{"b": map[string]any{"c": int64(1)}, "d": int64(2)},
+			},
+		},
+		{
+			name: "arrays of tables",
+			toml: `
+[[sinks]]
+type = "syslog"
+[sinks.headers]
+x = "1"
+
+[[sinks]]
+type = "loki"
+`,
+			want: map[string]any{"sinks": []any{
+				map[string]any{"type": "syslog", "headers": map[string]any{"x": "1"}},
+				map[string]any{"type": "loki"},
+			}},
+		},
+		{
+			name: "dotted keys",
+			toml: `
+rotation.max_size_mb = 10
+rotation . "max-backups" = 3
+"quoted.key" = 1
+'literal key' = 2
+`,
+			want: map[string]any{
+				"rotation":    map[string]any{"max_size_mb": int64(10), "max-backups": int64(3)},
+				"quoted.key":  int64(1),
+				"literal key": int64(2),
+			},
+		},
+		{
+			name: "inline tables and arrays",
+			toml: `
+headers = { Authorization = "Bearer x", a.b = 1 }
+empty = {}
+nested = [[1, 2], ["a"], [],]
+multiline = [
+  "a", # first
+  "b",
+]
+`,
+			want: map[string]any{
+				"headers":   map[string]any{"Authorization": "Bearer x", "a": map[string]any{"b": int64(1)}},
+				"empty":     map[string]any{},
+				"nested":    []any{[]any{int64(1), int64(2)}, []any{"a"}, []any{}},
+				"multiline": []any{"a", "b"},
+			},
+		},
+		{
+			name: "strings",
+			toml: `
+escapes = "tab\there \"quoted\" back\\slash \u00e9 \U0001F600 \e"
+literal = 'C:\logs\app.log'
+basic = """
+first line
+second "line"\
+    joined \
+    # not a comment"""
+raw = '''
+keep \n as is
+'''
+`,
+			want: map[string]any{
+				"escapes": "tab\there \"quoted\" back\\slash é 😀 \x1b",
+				"literal": `C:\logs\app.log`,
+				"basic":   "first line\nsecond \"line\"joined # not a comment",
+				"raw":     "keep \\n as is\n",
+			},
+		},
+		{
+			name: "crlf",
+			toml: "a = 1\r\n[b]\r\nc = \"\"\"\r\nx\"\"\"\r\n",
+			want: map[string]any{"a": int64(1), "b": map[string]any{"c": "x"}},
+		},
+	} {
+		got, err := parseTOML(tt.toml)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v\nwant %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, tt := range []struct {
+		toml string
+		err  string
+	}{
+		{"a = 1\nb", "line 2: expected = after b"},
+		{"a = 1\na = 2", "line 2: a is defined twice"},
+		{"[t]\n[t]", "line 2: table t is defined twice"},
+		{"a = 1\n[a.b]", "line 2: a is not a table"},
+		{"a = {}\n[[a]]", "line 2: a is not an array of tables"},
+		{"\n\na = \"open\nb = 1", "line 3: unterminated string"},
+		{"a = \"\"\"\nnever closed", "line 2: unterminated string"},
+		{`a = "\q"`, `line 1: invalid escape \q`},
+		{`a = "\u12"`, "line 1: invalid unicode escape"},
+		{`a = "\uD800"`, `line 1: invalid unicode escape \uD800`},
+		{"a = 1 b = 2", `line 1: unexpected 'b' after value`},
+		{"a = [1 2]", "line 1: expected , or ] in array"},
+		{"a = [1,\n2", "line 2: unterminated array"},
+		{"a = {b = 1 c = 2}", "line 1: expected , or } in inline table"},
+		{"a = 1979-05-27", "line 1: dates are not supported"},
+		{"a = 12abc", "line 1: invalid value 12abc"},
+		{"a = ", "line 1: expected a value"},
+		{"[a", `line 1: expected "]" after table name`},
+		{"[[a]", `line 1: expected "]]" after table name`},
+		{"= 1", `line 1: unexpected '=' in key`},
+	} {
+		_, err := parseTOML(tt.toml)
+		if err == nil {
+			t.Errorf("%q parsed", tt.toml)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got %q, want %q", tt.toml, err, tt.err)
+		}
+	}
+}
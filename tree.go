@@ -0,0 +1,337 @@
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+)
+
+type ValueStyle struct {
+	String lipgloss.Style
+	Number lipgloss.Style
+	Bool   lipgloss.Style
+	Nil    lipgloss.Style
+	More   lipgloss.Style
+}
+
+var ValueStyles = ValueStyle{
+	String: lipgloss.NewStyle(),
+	Number: lipgloss.NewStyle().Foreground(lipgloss.Color("141")),
+	Bool:   lipgloss.NewStyle().Foreground(lipgloss.Color("208")),
+	Nil:    lipgloss.NewStyle().Foreground(gray).Italic(true),
+	More:   lipgloss.NewStyle().Foreground(gray).Faint(true),
+}
+
+const (
+	defaultMaxDepth = 4
+	defaultMaxItems = 10
+	defaultMaxWidth = 120
+	maxAlignWidth   = 24
+)
+
+var guide = lipgloss.NewStyle().Foreground(gray).Faint(true)
+
+func plain(strs ...string) string {
+	return strings.Join(strs, " ")
+}
+
+type treeNode struct {
+	key   string
+	value any
+	more  int
+}
+
+type treeWriter struct {
+	b           *bytes.Buffer
+	seen        map[uintptr]bool
+	logger      *Logger
+	palette     *palette
+	keyStyle    lipgloss.Style
+	headerStyle lipgloss.Style
+	maxDepth    int
+	maxItems    int
+	maxWidth    int
+	width       int
+	align       bool
+	truncate    bool
+}
+
+func (f *PrettyFormatter) newTreeWriter(e *Entry, pal *palette, keyStyle lipgloss.Style) *treeWriter {
+	t := &treeWriter{
+		seen:        map[uintptr]bool{},
+		logger:      e.Logger,
+		palette:     pal,
+		keyStyle:    keyStyle,
+		headerStyle: lipgloss.NewStyle().Bold(true).Foreground(pal.levels[e.Level].Color),
+		maxDepth:    f.MaxDepth,
+		maxItems:    f.MaxItems,
+		maxWidth:    f.MaxWidth,
+		width:       f.width(e),
+		align:       f.AlignFields,
+		truncate:    f.Truncate,
+	}
+	if t.maxDepth <= 0 {
+		t.maxDepth = defaultMaxDepth
+	}
+	if t.maxItems <= 0 {
+		t.maxItems = defaultMaxItems
+	}
+	if t.maxWidth <= 0 {
+		t.maxWidth = defaultMaxWidth
+	}
+	return t
+}
+
+func (t *treeWriter) writeNodes(prefix string, nodes []treeNode, depth int) {
+	keyWidth := 0
+	if t.align {
+		for _, node := range nodes {
+			if w := lipgloss.Width(t.logger.sanitize(node.key)) + 2; w <= maxAlignWidth {
+				keyWidth = max(keyWidth, w)
+			}
+		}
+	}
+
+	for i, node := range nodes {
+		last := i == len(nodes)-1
+		connector, indent := "├─", "│  "
+		if last {
+			connector, indent = "└─", "   "
+		}
+		_, _ = fmt.Fprintf(t.b, "\n  %s%s ", t.palette.guide.Render(prefix), t.palette.guide.Render(connector))
+
+		if node.more > 0 {
+			t.b.WriteString(t.palette.values.More.Render(fmt.Sprintf("...%d more", node.more)))
+			continue
+		}
+
+		key := t.logger.sanitize(node.key)
+		ptr, isPtr := pointerOf(node.value)
+		if isPtr && t.seen[ptr] {
+			t.b.WriteString(t.keyStyle.Render(key + ": "))
+			t.b.WriteString(t.palette.values.More.Render(cycleMarker))
+			continue
+		}
+		if table, ok := tableOf(node.value); ok {
+			t.b.WriteString(t.keyStyle.Render(key))
+			t.writeTable("\n  "+t.palette.guide.Render(prefix+indent), 2+lipgloss.Width(prefix+indent), table)
+			continue
+		}
+		children, expanded := t.children(node.value, depth)
+		if !expanded {
+			value, render := t.scalar(node.value, depth == 0)
+			if key != "" && value != "" {
+				key += ": "
+			}
+			if value != "" && keyWidth > lipgloss.Width(key) {
+				key += strings.Repeat(" ", keyWidth-lipgloss.Width(key))
+			}
+			t.writeLeaf("\n  "+t.palette.guide.Render(prefix+indent), 2+lipgloss.Width(prefix+indent), key, value, render)
+			continue
+		}
+		t.b.WriteString(t.keyStyle.Render(key))
+		if isPtr {
+			t.seen[ptr] = true
+		}
+		t.writeNodes(prefix+indent, children, depth+1)
+		delete(t.seen, ptr)
+	}
+}
+
+// writeLeaf writes a key and its value, fitting the value to the output
+// width. Continuation lines repeat the tree guide in cont and are indented
+// to the value column; lead is the visible width of that guide.
+func (t *treeWriter) writeLeaf(cont string, lead int, key, value string, render func(...string) string) {
+	offset, width := lipgloss.Width(key), 0
+	if t.width > 0 {
+		width = t.width - lead - offset
+		if width < minWrapWidth && lipgloss.Width(value) > width {
+			// the key leaves no room, so start the value on its own line
+			t.b.WriteString(t.keyStyle.Render(strings.TrimRight(key, " ")))
+			t.b.WriteString(cont)
+			key, offset, width = "", 0, max(t.width-lead, minWrapWidth)
+		}
+	}
+	t.b.WriteString(t.keyStyle.Render(key))
+	for i, line := range fitLines(value, width, t.truncate) {
+		if i > 0 {
+			t.b.WriteString(cont + strings.Repeat(" ", offset))
+		}
+		t.b.WriteString(render(line))
+	}
+}
+
+// children expands maps, slices, arrays and structs into subtree nodes.
+// Values that know how to print themselves are left as scalars.
+func (t *treeWriter) children(value any, depth int) ([]treeNode, bool) {
+	if depth >= t.maxDepth {
+		return nil, false
+	}
+	switch value := value.(type) {
+	case Object:
+		if len(value) == 0 {
+			return nil, false
+		}
+		nodes := make([]treeNode, len(value))
+		for i, arg := range value {
+			nodes[i] = treeNode{key: arg.Key, value: arg.Value}
+		}
+		return t.limit(nodes), true
+	case nil, Raw, error, fmt.Stringer:
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+		if rv.CanInterface() {
+			switch rv.Interface().(type) {
+			case error, fmt.Stringer:
+				return nil, false
+			}
+		}
+	}
+
+	var nodes []treeNode
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, k := range keys {
+			nodes = append(nodes, treeNode{key: fmt.Sprint(k), value: rv.MapIndex(k).Interface()})
+		}
+
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+		for i := 0; i < rv.Len(); i++ {
+			nodes = append(nodes, treeNode{key: fmt.Sprintf("[%d]", i), value: rv.Index(i).Interface()})
+		}
+
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, hasOpts := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" && !hasOpts {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if strings.Contains(","+opts+",", ",omitempty,") && rv.Field(i).IsZero() {
+				continue
+			}
+			nodes = append(nodes, treeNode{key: name, value: rv.Field(i).Interface()})
+		}
+
+	default:
+		return nil, false
+	}
+
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	for i := range nodes {
+		nodes[i].value = resolve(nodes[i].value)
+	}
+	return t.limit(nodes), true
+}
+
+func (t *treeWriter) limit(nodes []treeNode) []treeNode {
+	if len(nodes) > t.maxItems {
+		more := len(nodes) - t.maxItems
+		nodes = append(nodes[:t.maxItems], treeNode{more: more})
+	}
+	return nodes
+}
+
+// scalar renders a leaf value. Composite values only end up here when they
+// are empty or the depth limit was reached, and are then shown as a marker.
+func (t *treeWriter) scalar(value any, top bool) (string, func(...string) string) {
+	switch value := value.(type) {
+	case nil:
+		if top {
+			return "", plain
+		}
+		return "nil", t.palette.values.Nil.Render
+	case Raw:
+		return string(value), plain
+	case error, fmt.Stringer:
+		return t.clip(t.logger.valueString(value)), t.palette.values.String.Render
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return "nil", t.palette.values.Nil.Render
+		}
+		rv = rv.Elem()
+	}
+
+	style := t.palette.values.String
+	switch rv.Kind() {
+	case reflect.Bool:
+		style = t.palette.values.Bool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		style = t.palette.values.Number
+	case reflect.Map:
+		if rv.IsNil() {
+			return "nil", t.palette.values.Nil.Render
+		}
+		if rv.Len() == 0 {
+			return "{}", t.palette.values.More.Render
+		}
+		return "{…}", t.palette.values.More.Render
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return "nil", t.palette.values.Nil.Render
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if rv.Len() == 0 {
+			return "[]", t.palette.values.More.Render
+		}
+		return "[…]", t.palette.values.More.Render
+	case reflect.Struct:
+		if !hasExportedFields(rv.Type()) {
+			return "{}", t.palette.values.More.Render
+		}
+		return "{…}", t.palette.values.More.Render
+	}
+
+	if rv.CanInterface() {
+		value = rv.Interface()
+	}
+	return t.clip(t.logger.valueString(value)), style.Render
+}
+
+func (t *treeWriter) clip(s string) string {
+	return truncate.StringWithTail(s, uint(t.maxWidth), "…")
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
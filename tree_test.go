@@ -0,0 +1,80 @@
+package clog
+
+import (
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City    string `json:"city"`
+	Zip     string `json:"zip,omitempty"`
+	Ignored string `json:"-"`
+	hidden  int
+}
+
+func treeOutput(t *testing.T, f *PrettyFormatter, key string, value any) string {
+	t.Helper()
+	l, b := bufferLogger()
+	l.SetFormatter(f)
+	l.Info().Any(key, value).Msg("m")
+	return uncolored(b.String())
+}
+
+func TestTreeExpandsValues(t *testing.T) {
+	out := treeOutput(t, &PrettyFormatter{}, "user", map[string]any{
+		"name": "ann",
+		"tags": []string{"a", "b"},
+		"addr": address{City: "Oslo"},
+		"none": nil,
+	})
+	want := `• m
+  └─ user
+     ├─ addr
+     │  └─ city: Oslo
+     ├─ name: ann
+     ├─ none: nil
+     └─ tags
+        ├─ [0]: a
+        └─ [1]: b
+`
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestTreeLimits(t *testing.T) {
+	out := treeOutput(t, &PrettyFormatter{MaxItems: 2}, "n", []int{1, 2, 3, 4})
+	if !strings.Contains(out, "[1]: 2") || strings.Contains(out, "[2]") || !strings.Contains(out, "...2 more") {
+		t.Errorf("items not capped: %q", out)
+	}
+
+	nested := map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}}
+	out = treeOutput(t, &PrettyFormatter{MaxDepth: 2}, "d", nested)
+	if !strings.Contains(out, "b: {…}") || strings.Contains(out, "c: 1") {
+		t.Errorf("depth not capped: %q", out)
+	}
+
+	out = treeOutput(t, &PrettyFormatter{MaxWidth: 5}, "s", strings.Repeat("x", 20))
+	if !strings.Contains(out, "s: xxxx…") {
+		t.Errorf("width not capped: %q", out)
+	}
+}
+
+func TestTreeScalars(t *testing.T) {
+	var nilMap map[string]int
+	for _, tt := range []struct {
+		value any
+		want  string
+	}{
+		{[]int{}, "v: []"},
+		{map[string]int{}, "v: {}"},
+		{nilMap, "v: nil"},
+		{[]byte("hi"), "v: [104 105]"},
+		{struct{ hidden int }{1}, "v: {}"},
+		{true, "v: true"},
+	} {
+		if out := treeOutput(t, &PrettyFormatter{}, "v", tt.value); !strings.Contains(out, tt.want) {
+			t.Errorf("%#v: got %q, want %q", tt.value, out, tt.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package clog
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type ObjectEncoder interface {
+	Add(key string, value any)
+}
+
+// ObjectMarshaler lets a type decide how it is logged, e.g. to hide PII or
+// add computed fields, without converting it at every call site.
+type ObjectMarshaler interface {
+	MarshalLogObject(enc ObjectEncoder) error
+}
+
+type ObjectMarshalerFunc func(enc ObjectEncoder) error
+
+func (f ObjectMarshalerFunc) MarshalLogObject(enc ObjectEncoder) error {
+	return f(enc)
+}
+
+// Object is an ordered set of fields produced by an ObjectMarshaler or a
+// slog group. Every formatter renders it as a nested object.
+type Object []Argument
+
+func (o *Object) Add(key string, value any) {
+	*o = append(*o, Argument{Key: key, Value: value})
+}
+
+func (o Object) String() string {
+	parts := make([]string, len(o))
+	for i, arg := range o {
+		parts[i] = fmt.Sprintf("%s=%v", arg.Key, arg.Value)
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+func (o Object) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, arg := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := marshalJSON(arg.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+const (
+	maxResolveDepth = 8
+	cycleMarker     = "<cycle>"
+	nilMarker       = "<nil>"
+)
+
+// resolve turns a field value into what should be logged for it, in this
+// order of precedence:
+//
+//  1. ObjectMarshaler, rendered as an Object
+//  2. slog.LogValuer, resolved and converted (groups become Objects)
+//  3. error, rendered as its message
+//  4. fmt.Stringer, rendered as its string
+//
+// Raw and Secret values are kept as they are. Marshalers, valuers and
+// errors inside maps, slices and structs are resolved too, see
+// resolveNested. Values reached again through their own marshaler, or
+// nested deeper than maxResolveDepth, are replaced by a cycle marker.
+func resolve(value any) any {
+	return resolveValue(value, 0, nil)
+}
+
+func resolveValue(value any, depth int, seen []uintptr) any {
+	switch value.(type) {
+	case ObjectMarshaler, slog.LogValuer, error, fmt.Stringer:
+		// like fmt, print typed nil pointers rather than call their methods
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nilMarker
+		}
+	}
+
+	switch v := value.(type) {
+	case nil, Raw, Secret, string:
+		return value
+	case Object:
+		obj := make(Object, len(v))
+		for i, arg := range v {
+			obj[i] = Argument{Key: arg.Key, Value: resolveValue(arg.Value, depth+1, seen)}
+		}
+		return obj
+	case Table:
+		rows := make([][]any, len(v.Rows))
+		for i, row := range v.Rows {
+			rows[i] = make([]any, len(row))
+			for j, cell := range row {
+				rows[i][j] = resolveValue(cell, depth+1, seen)
+			}
+		}
+		return Table{Headers: v.Headers, Rows: rows}
+	case ObjectMarshaler, slog.LogValuer:
+		if depth >= maxResolveDepth {
+			return cycleMarker
+		}
+		if ptr, ok := pointerOf(value); ok {
+			for _, p := range seen {
+				if p == ptr {
+					return cycleMarker
+				}
+			}
+			seen = append(seen, ptr)
+		}
+	}
+
+	switch v := value.(type) {
+	case ObjectMarshaler:
+		var obj Object
+		if err := v.MarshalLogObject(&obj); err != nil {
+			obj.Add("!error", err.Error())
+		}
+		for i := range obj {
+			obj[i].Value = resolveValue(obj[i].Value, depth+1, seen)
+		}
+		return obj
+	case slog.LogValuer:
+		return slogValue(v.LogValue(), depth+1, seen)
+	case slog.Value:
+		return slogValue(v, depth+1, seen)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if nested, ok := resolveNested(reflect.ValueOf(value), depth, seen); ok {
+		return nested
+	}
+	return value
+}
+
+// resolveNested resolves the marshalers, valuers and errors held in maps,
+// slices and structs, so that what they hide is hidden from every formatter
+// and sink. Only containers holding one are rebuilt: maps and structs as
+// Objects, with their JSON field names, and slices as []any. It reports
+// whether rv was rebuilt.
+func resolveNested(rv reflect.Value, depth int, seen []uintptr) (any, bool) {
+	if depth >= maxResolveDepth {
+		return nil, false
+	}
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		if rv.Kind() == reflect.Pointer {
+			if slices.Contains(seen, rv.Pointer()) {
+				return nil, false
+			}
+			seen = append(seen, rv.Pointer())
+		}
+		rv = rv.Elem()
+	}
+
+	var obj Object
+	var changed bool
+	add := func(key string, v reflect.Value) {
+		value, ok := resolveElem(v, depth, seen)
+		obj.Add(key, value)
+		changed = changed || ok
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, k := range keys {
+			add(fmt.Sprint(k), rv.MapIndex(k))
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+		for i := 0; i < rv.Len(); i++ {
+			add("", rv.Index(i))
+		}
+		if !changed {
+			return nil, false
+		}
+		list := make([]any, len(obj))
+		for i, arg := range obj {
+			list[i] = arg.Value
+		}
+		return list, true
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			name, opts, hasOpts := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" && !hasOpts {
+				continue
+			}
+			if strings.Contains(","+opts+",", ",omitempty,") && rv.Field(i).IsZero() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			add(name, rv.Field(i))
+		}
+	}
+	return obj, changed
+}
+
+// resolveElem resolves a value found in a container, reporting whether it
+// changed. Stringers are left alone, as formatters render them anyway and
+// JSON may encode them otherwise.
+func resolveElem(v reflect.Value, depth int, seen []uintptr) (any, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	value := v.Interface()
+	switch value.(type) {
+	case nil, Raw, Secret, string:
+		return value, false
+	case ObjectMarshaler, slog.LogValuer, error:
+		return resolveValue(value, depth+1, seen), true
+	}
+	if nested, ok := resolveNested(v, depth+1, seen); ok {
+		return nested, true
+	}
+	return value, false
+}
+
+func slogValue(v slog.Value, depth int, seen []uintptr) any {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		obj := make(Object, 0, len(attrs))
+		for _, attr := range attrs {
+			obj.Add(attr.Key, slogValue(attr.Value, depth+1, seen))
+		}
+		return obj
+	case slog.KindAny:
+		return resolveValue(v.Any(), depth, seen)
+	}
+	return v.Any()
+}
+
+func pointerOf(value any) (uintptr, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return 0, false
+		}
+		return rv.Pointer(), true
+	}
+	return 0, false
+}
+
+func (l *Logger) resolve(e *Entry) {
+	for it := e.Fields.Front(); it != nil; it = it.Next() {
+		e.Fields.Set(it.Key, resolve(it.Value))
+	}
+}
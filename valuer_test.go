@@ -0,0 +1,103 @@
+package clog
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type account struct {
+	ID    int
+	Email string
+}
+
+func (a account) MarshalLogObject(enc ObjectEncoder) error {
+	enc.Add("id", a.ID)
+	enc.Add("email", Secret(a.Email))
+	return nil
+}
+
+type token string
+
+func (t token) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("prefix", string(t)[:3]), slog.Int("len", len(t)))
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func (n *node) MarshalLogObject(enc ObjectEncoder) error {
+	enc.Add("name", n.Name)
+	enc.Add("next", n.Next)
+	return nil
+}
+
+type stringer struct{ s string }
+
+func (s *stringer) String() string { return s.s }
+
+func TestResolve(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		value any
+		want  any
+	}{
+		{"marshaler", account{7, "a@b.c"}, Object{{"id", 7}, {"email", Secret("a@b.c")}}},
+		{"valuer", token("abcdef"), Object{{"prefix", "abc"}, {"len", int64(6)}}},
+		{"error", errors.New("boom"), "boom"},
+		{"stringer", &stringer{"s"}, "s"},
+		{"raw", Raw("r"), Raw("r")},
+		{"nested", map[string]any{"err": errors.New("e"), "n": 1}, Object{{"err", "e"}, {"n", 1}}},
+		{"slice", []any{errors.New("e"), 2}, []any{"e", 2}},
+		{"plain", map[string]int{"a": 1}, map[string]int{"a": 1}},
+	} {
+		if got := resolve(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveCycle(t *testing.T) {
+	n := &node{Name: "a"}
+	n.Next = n
+	got := resolve(n)
+	want := Object{{"name", "a"}, {"next", cycleMarker}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v", got)
+	}
+}
+
+func TestResolveTypedNil(t *testing.T) {
+	var err *url.Error
+	for _, value := range []any{(*url.URL)(nil), (*node)(nil), (*stringer)(nil), err} {
+		if got := resolve(value); got != "<nil>" {
+			t.Errorf("%T: got %#v", value, got)
+		}
+	}
+
+	l, b := bufferLogger()
+	l.Info().Any("u", (*url.URL)(nil)).Err(err).Msg("hi")
+	out := uncolored(b.String())
+	if !strings.Contains(out, "u: <nil>") || !strings.Contains(out, "err: <nil>") {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestResolveBeforeFormatting(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetFormatter(&JSONFormatter{})
+	l.Info().Any("account", account{7, "a@b.c"}).Msg("m")
+	var got struct{ Account map[string]any }
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Account["id"] != 7.0 || got.Account["email"] != "****" {
+		t.Errorf("got %s", b.String())
+	}
+}
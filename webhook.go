@@ -0,0 +1,174 @@
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// WebhookMessage is what a webhook template renders: the entries
+// aggregated into one message, and Text, a plain text rendering of them.
+type WebhookMessage struct {
+	Entries []WebhookEntry
+	Text    string
+}
+
+type WebhookEntry struct {
+	Time    time.Time `json:"time"`
+	Level   Level     `json:"level"`
+	Message string    `json:"msg"`
+	Caller  string    `json:"caller,omitempty"`
+	Fields  Object    `json:"fields,omitempty"`
+}
+
+// WebhookPreset is the payload template and content type a kind of
+// incoming webhook expects.
+type WebhookPreset struct {
+	Template    string
+	ContentType string
+}
+
+var (
+	SlackWebhook = WebhookPreset{
+		Template:    `{"text": {{json (slack .Text)}}}`,
+		ContentType: "application/json",
+	}
+	MattermostWebhook = WebhookPreset{
+		Template:    `{"text": {{json (slack .Text)}}}`,
+		ContentType: "application/json",
+	}
+	DiscordWebhook = WebhookPreset{
+		Template:    `{"content": {{json (truncate 2000 .Text)}}}`,
+		ContentType: "application/json",
+	}
+	JSONWebhook = WebhookPreset{
+		Template:    `{{json .Entries}}`,
+		ContentType: "application/json",
+	}
+)
+
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := marshalJSON(v)
+		return string(data), err
+	},
+	"truncate": truncateText,
+	"slack":    slackReplacer.Replace,
+}
+
+// slackReplacer escapes the characters Slack and Mattermost give a meaning
+// to in message text, so "<!channel>" in a log message pings no one.
+var slackReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// truncateText shortens s to n characters, the last of them an ellipsis.
+func truncateText(n int, s string) string {
+	if n <= 0 {
+		return ""
+	}
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+// ParseWebhookTemplate parses a payload template over a WebhookMessage. On
+// top of the standard functions it can use json, to encode a value,
+// truncate, to shorten a string to a number of characters, and slack, to
+// escape text for Slack and Mattermost.
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(webhookFuncs).Parse(text)
+}
+
+// WebhookSink posts entries at Level or above to an incoming webhook, such
+// as a chat channel's. Entries are aggregated into one message per batch:
+// up to BatchSize entries logged within BatchInterval. Messages are sent at
+// most once per MinInterval, from a goroutine, so a slow webhook never
+// holds up logging.
+type WebhookSink struct {
+	Batching
+	URL         string
+	Level       Level
+	Template    *template.Template
+	ContentType string
+	Headers     map[string]string
+	MinInterval time.Duration
+	Client      *http.Client
+
+	batcher batcher[WebhookEntry]
+	last    time.Time
+}
+
+func NewWebhookSink(url string, preset WebhookPreset) *WebhookSink {
+	batching := defaultBatching()
+	batching.BatchSize = 10
+	batching.BatchInterval = 10 * time.Second
+	batching.QueueSize = 256
+	return &WebhookSink{
+		Batching:    batching,
+		URL:         url,
+		Level:       LevelError,
+		Template:    template.Must(ParseWebhookTemplate(preset.Template)),
+		ContentType: preset.ContentType,
+		MinInterval: time.Second,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *WebhookSink) WriteEntry(e *Entry) error {
+	if e.Level < s.Level {
+		return nil
+	}
+	s.batcher.add(s.Batching, WebhookEntry{
+		Time:    e.Time,
+		Level:   e.Level,
+		Message: e.Message,
+		Caller:  e.Caller,
+		Fields:  Object(sinkFields(e)),
+	}, s.send)
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (s *WebhookSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// Close posts the queued entries and stops the sink.
+func (s *WebhookSink) Close() error {
+	s.batcher.close()
+	return nil
+}
+
+func (s *WebhookSink) send(entries []WebhookEntry) error {
+	var payload bytes.Buffer
+	if err := s.Template.Execute(&payload, WebhookMessage{Entries: entries, Text: webhookText(entries)}); err != nil {
+		return err
+	}
+	if wait := s.MinInterval - time.Since(s.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	s.last = time.Now()
+	return postHTTP(s.Client, s.URL, s.ContentType, s.Headers, payload.Bytes())
+}
+
+func webhookText(entries []WebhookEntry) string {
+	var b strings.Builder
+	for i, entry := range entries {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "[%s] %s", entry.Level, entry.Message)
+		var fields bytes.Buffer
+		for _, field := range entry.Fields {
+			writeLogfmtField(&fields, logfmtKey(field.Key), field.Value.(string))
+		}
+		if fields.Len() > 0 {
+			b.WriteString("\n    ")
+			b.Write(fields.Bytes())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package clog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTruncateText(t *testing.T) {
+	for _, tt := range []struct {
+		n    int
+		s    string
+		want string
+	}{
+		{5, "hello", "hello"},
+		{4, "hello", "hel…"},
+		{1, "hello", "…"},
+		{0, "hello", ""},
+		{-1, "hello", ""},
+		{3, "héllo", "hé…"},
+	} {
+		if got := truncateText(tt.n, tt.s); got != tt.want {
+			t.Errorf("truncateText(%d, %q) = %q, want %q", tt.n, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookSlack(t *testing.T) {
+	srv, requests := collector(t)
+	s := NewWebhookSink(srv.URL, SlackWebhook)
+	s.WriteEntry(testEntry(LevelInfo, "ignored"))
+	s.WriteEntry(testEntry(LevelError, "failed").Any("user", "ann"))
+	s.WriteEntry(testEntry(LevelFatal, "gave up"))
+	s.Close()
+
+	req := <-requests
+	if got := req.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var body struct{ Text string }
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatalf("%v: %s", err, req.body)
+	}
+	if want := "[error] failed\n    user=ann\n[fatal] gave up"; body.Text != want {
+		t.Errorf("got %q, want %q", body.Text, want)
+	}
+	if len(requests) != 0 {
+		t.Errorf("got %d more requests, want the batch in one", len(requests))
+	}
+}
+
+func TestWebhookSlackEscapes(t *testing.T) {
+	for name, preset := range map[string]WebhookPreset{
+		"slack":      SlackWebhook,
+		"mattermost": MattermostWebhook,
+	} {
+		srv, requests := collector(t)
+		s := NewWebhookSink(srv.URL, preset)
+		s.WriteEntry(testEntry(LevelError, "<!channel> a & b > c"))
+		s.Close()
+
+		var body struct{ Text string }
+		if err := json.Unmarshal((<-requests).body, &body); err != nil {
+			t.Fatal(err)
+		}
+		if want := "[error] &lt;!channel&gt; a &amp; b &gt; c"; body.Text != want {
+			t.Errorf("%s: got %q, want %q", name, body.Text, want)
+		}
+	}
+}
+
+func TestWebhookDiscordTruncates(t *testing.T) {
+	srv, requests := collector(t)
+	s := NewWebhookSink(srv.URL, DiscordWebhook)
+	s.WriteEntry(testEntry(LevelError, strings.Repeat("x", 3000)))
+	s.Close()
+
+	var body struct{ Content string }
+	if err := json.Unmarshal((<-requests).body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if n := len([]rune(body.Content)); n != 2000 || !strings.HasSuffix(body.Content, "…") {
+		t.Errorf("got %d characters", n)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	srv, requests := collector(t)
+	s := NewWebhookSink(srv.URL, JSONWebhook)
+	tmpl, err := ParseWebhookTemplate(`{{range .Entries}}{{truncate 0 .Message}}|{{truncate 3 .Message}};{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Template = tmpl
+	s.MinInterval = 0
+	s.WriteEntry(testEntry(LevelError, "failed"))
+	s.Close()
+
+	if got := string((<-requests).body); got != "|fa…;" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,33 @@
+package clog
+
+import (
+	"strings"
+
+	"github.com/muesli/reflow/truncate"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/reflow/wrap"
+)
+
+// minWrapWidth is the narrowest column worth wrapping into; anything less
+// and values move to their own continuation lines instead.
+const minWrapWidth = 16
+
+// fitLines splits text into lines no wider than width, word wrapping (and
+// hard wrapping words that are too long) or, with cut set, truncating each
+// line with an ellipsis. A width of zero leaves lines as they are.
+func fitLines(text string, width int, cut bool) []string {
+	lines := strings.Split(text, "\n")
+	if width <= 0 {
+		return lines
+	}
+	fitted := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if cut {
+			fitted = append(fitted, truncate.StringWithTail(line, uint(width), "…"))
+			continue
+		}
+		line = wrap.String(wordwrap.String(line, width), width)
+		fitted = append(fitted, strings.Split(line, "\n")...)
+	}
+	return fitted
+}
@@ -0,0 +1,62 @@
+package clog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFitLines(t *testing.T) {
+	for _, tt := range []struct {
+		text  string
+		width int
+		cut   bool
+		want  []string
+	}{
+		{"one two three", 0, false, []string{"one two three"}},
+		{"one two three", 8, false, []string{"one two", "three"}},
+		{"abcdefghij", 4, false, []string{"abcd", "efgh", "ij"}},
+		{"one two three", 8, true, []string{"one two…"}},
+		{"a\nb", 8, false, []string{"a", "b"}},
+	} {
+		if got := fitLines(tt.text, tt.width, tt.cut); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fitLines(%q, %d, %v) = %q, want %q", tt.text, tt.width, tt.cut, got, tt.want)
+		}
+	}
+}
+
+func TestWrapOutput(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetWidth(30)
+	l.Info().Any("key", "alpha beta gamma delta epsilon").Msg("the quick brown fox jumps over")
+	out := uncolored(b.String())
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if len([]rune(line)) > 30 {
+			t.Errorf("line wider than 30: %q", line)
+		}
+	}
+	if !strings.HasPrefix(out, "• the quick brown fox jumps\n  over\n") {
+		t.Errorf("message not indented under itself: %q", out)
+	}
+	if !strings.Contains(out, "└─ key: alpha beta gamma\n          delta epsilon\n") {
+		t.Errorf("value not wrapped to its column: %q", out)
+	}
+
+	b.Reset()
+	l.SetWidth(-1)
+	l.Info().Msg(strings.Repeat("x ", 100))
+	if strings.Count(b.String(), "\n") != 1 {
+		t.Errorf("wrapped with a negative width: %q", b.String())
+	}
+}
+
+func TestTruncateAndAlign(t *testing.T) {
+	l, b := bufferLogger()
+	l.SetWidth(30).SetFormatter(&PrettyFormatter{Truncate: true, AlignFields: true})
+	l.Info().Any("a", "short").Any("long", strings.Repeat("y", 40)).Msg("m")
+	out := uncolored(b.String())
+	want := "• m\n  ├─ a:    short\n  └─ long: yyyyyyyyyyyyyyyyyy…\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}